@@ -0,0 +1,38 @@
+// Command checkconfig checks GoShell config files without starting the
+// shell, it runs on every system, e.g. in a pre-commit hook:
+//
+//	checkconfig config.yaml
+//
+// Every problem of the files and their includes is printed like a compiler
+// does as file:line:column: message, the exit code is 1 if something was
+// found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"GoShell/config"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: checkconfig config.yaml...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	code := 0
+	for _, file := range flag.Args() {
+		for _, e := range config.CheckConfigFile(file) {
+			fmt.Printf("%s:%d:%d: %s\n", e.File, e.Line, e.Column, e.Msg)
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,118 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"GoShell/config"
+	"github.com/leaanthony/winc"
+	"github.com/leaanthony/winc/w32"
+	"golang.org/x/sys/windows"
+)
+
+// shellCommand runs a built-in command like "!reload" for the "command"
+// action, the names and arguments are in config.Commands
+type shellCommand func(s *shell, args []string) error
+
+// the names are written as in config.Commands
+var shellCommands map[string]shellCommand
+
+// in init because the commands refer to the functions that run them
+func init() {
+	shellCommands = map[string]shellCommand{
+		"!reload": func(s *shell, _ []string) error {
+			s.Reload()
+			return nil
+		},
+		"!quit": func(_ *shell, _ []string) error {
+			winc.Exit()
+			return nil
+		},
+		"!toggleTaskbar": func(s *shell, args []string) error {
+			switch strings.ToLower(argOrEmpty(args, 0)) {
+			case "":
+				s.taskbarHidden = !s.taskbarHidden
+			case "show":
+				s.taskbarHidden = false
+			case "hide":
+				s.taskbarHidden = true
+			default:
+				return fmt.Errorf("unknown argument %q", args[0])
+			}
+			s.LayoutTaskbar()
+			return nil
+		},
+		"!showDesktopMenu": func(s *shell, _ []string) error {
+			h := s.mainWindow.Handle()
+			w32.SetForegroundWindow(h) // otherwise the menu doesn't close when clicking somewhere else
+			w32.SendMessage(h, w32.WM_CONTEXTMENU, h, 0)
+			return nil
+		},
+		"!showTaskMenu": func(s *shell, _ []string) error {
+			h := s.mainWindow.Handle()
+			w32.SetForegroundWindow(h)
+			const MK_MBUTTON = 0x0010
+			w32.SendMessage(h, w32.WM_MBUTTONDOWN, MK_MBUTTON, 0)
+			return nil
+		},
+		"!minimizeAll": func(s *shell, _ []string) error {
+			for _, btn := range s.TaskbarWindow.tl.PushButtonList {
+				if w32.IsWindowVisible(btn.hWnd) && !w32.IsIconic(btn.hWnd) {
+					w32.ShowWindow(w32.HWND(btn.hWnd), w32.SW_MINIMIZE)
+				}
+			}
+			return nil
+		},
+		"!restoreAll": func(s *shell, _ []string) error {
+			for _, btn := range s.TaskbarWindow.tl.PushButtonList {
+				if w32.IsIconic(btn.hWnd) {
+					w32.ShowWindow(w32.HWND(btn.hWnd), w32.SW_RESTORE)
+				}
+			}
+			return nil
+		},
+		"!lock": func(_ *shell, _ []string) error {
+			if !w32.LockWorkStation() {
+				return fmt.Errorf("LockWorkStation failed")
+			}
+			return nil
+		},
+		"!logoff": func(_ *shell, args []string) error {
+			// https://learn.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-exitwindowsex
+			var flags uint32 = windows.EWX_LOGOFF
+			switch strings.ToLower(argOrEmpty(args, 0)) {
+			case "":
+			case "force":
+				flags |= windows.EWX_FORCEIFHUNG
+			default:
+				return fmt.Errorf("unknown argument %q", args[0])
+			}
+			return windows.ExitWindowsEx(flags, 0)
+		},
+	}
+}
+
+// RunCommand runs a built-in command
+func (s *shell) RunCommand(name string, args []string) error {
+	name, ok := config.LookupCommand(name)
+	run := shellCommands[name]
+	if !ok || run == nil {
+		return fmt.Errorf("unknown command")
+	}
+	if err := run(s, args); err != nil {
+		if args := config.Commands[name]; args != "" {
+			return fmt.Errorf("%w, arguments: %s", err, args)
+		}
+		return err
+	}
+	return nil
+}
+
+func argOrEmpty(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
@@ -1,82 +1,24 @@
+//go:build windows
+
 package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strings"
+
+	"GoShell/config"
 
 	"github.com/leaanthony/winc/w32"
 	"golang.org/x/sys/windows"
-	"gopkg.in/yaml.v2"
 )
 
-type Config struct {
-	Desktop struct {
-		Contextmenu struct {
-			DarkMode      bool `yaml:"darkMode"`
-			AddDebugEntry bool `yaml:"addDebugEntry"`
-		} `yaml:"contextmenu"`
-	} `yaml:"desktop"`
-	Taskbar struct {
-		FontFamily   string `yaml:"fontFamily"`
-		FontSize     int    `yaml:"fontSize"`
-		Position     string `yaml:"position"`
-		IconPosition string `yaml:"iconPosition"`
-		Height       int    `yaml:"height"`
-		Button       struct {
-			Size struct {
-				Width  int `yaml:"width"`
-				Height int `yaml:"height"`
-			} `yaml:"size"`
-			Bgcolor struct {
-				R int `yaml:"r"`
-				G int `yaml:"g"`
-				B int `yaml:"b"`
-			} `yaml:"bgcolor"`
-			Textcolor struct {
-				R int `yaml:"r"`
-				G int `yaml:"g"`
-				B int `yaml:"b"`
-			} `yaml:"textcolor"`
-		} `yaml:"button"`
-		Bgcolor struct {
-			A int `yaml:"a"`
-			R int `yaml:"r"`
-			G int `yaml:"g"`
-			B int `yaml:"b"`
-		} `yaml:"bgcolor"`
-	} `yaml:"taskbar"`
-	Contextmenu []Contextmenu `yaml:"contextmenu"`
-	Hotkey      []struct {
-		Buttons       string   `yaml:"buttons"`
-		ShellExecute  string   `yaml:"shellExecute,omitempty"`
-		CreateProcess string   `yaml:"createProcess,omitempty"`
-		OpenProcess   string   `yaml:"openProcess,omitempty"`
-		Args          []string `yaml:"args,omitempty"`
-		Hidden        bool     `yaml:"hidden,omitempty"`
-	} `yaml:"hotkey"`
-}
-
-type Contextmenu struct {
-	Name   string   `yaml:"name"`
-	Args   []string `yaml:"args,omitempty"`
-	Path   []string `yaml:"path,omitempty"`
-	Hidden bool     `yaml:"hidden,omitempty"`
-	Icon   struct {
-		Filename string `yaml:"filename"`
-		Index    int    `yaml:"index"`
-	} `yaml:"icon,omitempty"`
-	ShellExecute  string `yaml:"shellExecute,omitempty"`
-	CreateProcess string `yaml:"createProcess,omitempty"`
-	OpenProcess   string `yaml:"openProcess,omitempty"`
-}
-
 var (
-	exPath string
-	config *Config
+	exPath     string
+	configPath string
+	cfg        *config.Config
 )
 
 func init() {
@@ -84,37 +26,69 @@ func init() {
 
 	ex, err := os.Executable()
 	if err != nil {
-		w32.MessageBox(0, "Load config.yaml", err.Error(), w32.MB_ICONERROR)
+		w32.MessageBox(0, err.Error(), "Load config.yaml", w32.MB_ICONERROR)
 		panic(err)
 	}
 	exPath = filepath.Dir(ex)
+	config.KnownFolder = knownFolder
 
+	configPtr := flag.String("config", "", "use this config file instead of looking for config.yaml")
+	checkConfigPtr := flag.String("check-config", "", "check this config file and its includes, print the problems and exit")
 	noFilesPtr := flag.Bool("nofiles", false, "do not create RegFiles")
 	startUpPtr := flag.Bool("startup", false, "start Autorun")
+	startUpDryRunPtr := flag.Bool("startup-dry-run", false, "print what -startup would start, in its order, and exit")
+	printConfigPtr := flag.Bool("print-config", false, "print the effective config with all defaults and exit")
+	printFormatPtr := flag.String("print-format", "yaml", "format of -print-config: yaml or json")
 	flag.Parse()
-	if !*noFilesPtr {
-		createRegFiles()
+	if *checkConfigPtr != "" {
+		// like cmd/checkconfig
+		errs := config.CheckConfigFile(*checkConfigPtr)
+		for _, e := range errs {
+			fmt.Printf("%s:%d:%d: %s\n", e.File, e.Line, e.Column, e.Msg)
+		}
+		if errs != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
 	}
+	configPath = config.FindConfig(*configPtr, exPath)
+	log.Println("config", configPath)
 
-	if *startUpPtr {
-		go startup()
+	if *printConfigPtr {
+		c, err := config.ReadConfig(configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if err := config.PrintConfig(os.Stdout, c, *printFormatPtr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if *startUpDryRunPtr {
+		c, err := config.ReadConfig(configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if err := startupDryRun(os.Stdout, c); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if !*noFilesPtr {
+		createRegFiles()
 	}
 
 	// https://devblogs.microsoft.com/oldnewthing/20230608-00/?p=108312
 	// https://learn.microsoft.com/en-us/windows/win32/api/winbase/nf-winbase-registerapplicationrestart
 	w32.RegisterApplicationRestart(ex, w32.RESTART_NO_PATCH|w32.RESTART_NO_REBOOT)
 
-	config = LoadConfig()
-}
-
-var environmentVariablesRegEx = regexp.MustCompile(`%(\w+)%`)
+	cfg = LoadConfig()
 
-func ResolveVariables(data string) string {
-	data = strings.ToLower(data)
-	for _, ev := range environmentVariablesRegEx.FindAllString(data, -1) {
-		data = strings.ReplaceAll(data, ev, os.Getenv(strings.Trim(ev, "%")))
+	if *startUpPtr {
+		go startup(cfg) // after the config, the files are opened with its fileHandlers
 	}
-	return data
 }
 
 // https://learn.microsoft.com/de-de/windows/win32/shell/knownfolderid
@@ -262,53 +236,24 @@ var FOLDERIDs = map[string]*windows.KNOWNFOLDERID{
 	"FOLDERID_AppDataProgramData":     windows.FOLDERID_AppDataProgramData,
 }
 
-func LoadConfig() *Config {
-	content, err := os.ReadFile(filepath.Join(exPath, "config.yaml"))
-	if err != nil {
-		w32.MessageBox(0, "Load config.yaml", err.Error(), w32.MB_ICONERROR)
-		log.Println(err)
-	}
-	c := Config{}
-	err = yaml.Unmarshal(content, &c)
-	if err != nil {
-		w32.MessageBox(0, "Load config.yaml", err.Error(), w32.MB_ICONERROR)
-		log.Printf("cannot unmarshal data: %v", err)
-	}
-
-	// Default Values
-	if c.Taskbar.Height == 0 {
-		c.Taskbar.Height = 30
-	}
-
-	if c.Taskbar.FontFamily == "" {
-		c.Taskbar.FontFamily = "Segoe UI"
+// knownFolder returns the path of a name of FOLDERIDs for config.KnownFolder
+func knownFolder(name string) (string, bool) {
+	id, ok := FOLDERIDs[name]
+	if !ok {
+		return "", false
 	}
-	if c.Taskbar.FontSize == 0 {
-		c.Taskbar.FontSize = 9
-	}
-
-	if c.Taskbar.Button.Size.Width == 0 {
-		c.Taskbar.Button.Size.Width = 160
-	}
-	if c.Taskbar.Button.Size.Height == 0 {
-		c.Taskbar.Button.Size.Height = 30
-	}
-
-	c.Taskbar.IconPosition = strings.ToLower(c.Taskbar.IconPosition)
-	c.Taskbar.Position = strings.ToLower(c.Taskbar.Position)
+	p := getKnownFolderPath(id)
+	return p, p != ""
+}
 
-	// resolve file paths
-	for i := 0; i < len(c.Contextmenu); i++ {
-		for j := 0; j < len(c.Contextmenu[i].Path); j++ {
-			temp := c.Contextmenu[i].Path[j]
-			val, ok := FOLDERIDs[temp]
-			if ok {
-				c.Contextmenu[i].Path[j] = getKnownFolderPath(val)
-			} else {
-				c.Contextmenu[i].Path[j] = ResolveVariables(temp)
-			}
-		}
+// LoadConfig reads the config.yaml found by config.FindConfig. Errors are shown
+// to the user, GoShell then continues with whatever could be read.
+func LoadConfig() *config.Config {
+	c, err := config.ReadConfig(configPath)
+	if err != nil {
+		w32.MessageBox(0, err.Error(), "Load config.yaml", w32.MB_ICONERROR)
+		log.Println(err)
 	}
-
-	return &c
+	offerMigration(c)
+	return c
 }
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Action is what an entry of the contextmenu or a hotkey does when it is
+// used, exactly one of the action kinds is set.
+type Action struct {
+	ShellExecute  string `yaml:"shellExecute,omitempty"`
+	CreateProcess string `yaml:"createProcess,omitempty"`
+	OpenProcess   string `yaml:"openProcess,omitempty"`
+	// built-in command like "!reload", see commands.go
+	Command string   `yaml:"command,omitempty"`
+	Args    []string `yaml:"args,omitempty"`
+	// used as is instead of args, for programs with their own quoting rules
+	CommandLine string `yaml:"commandLine,omitempty"`
+	Hidden      bool   `yaml:"hidden,omitempty"`
+	Workdir     string `yaml:"workdir,omitempty"`
+	// merged into the environment of GoShell, an empty value removes the variable
+	Env  map[string]string `yaml:"env,omitempty"`
+	Show string            `yaml:"show,omitempty"` // normal, minimized, maximized or hidden
+	Verb string            `yaml:"verb,omitempty"` // open, runas, edit or print
+	// activate a window of the program if there is one instead of starting it
+	FocusExisting *FocusExisting `yaml:"focusExisting,omitempty"`
+}
+
+// FocusExisting activates a window of the program instead of starting it again
+type FocusExisting struct {
+	Exe        string `yaml:"exe,omitempty"`        // file name or full path of the program
+	Class      string `yaml:"class,omitempty"`      // window class, e.g. CabinetWClass
+	TitleRegex string `yaml:"titleRegex,omitempty"` // https://pkg.go.dev/regexp/syntax
+	Cycle      bool   `yaml:"cycle,omitempty"`      // step through the matches on every use
+}
+
+// actionKinds are the yaml keys of the action kinds, a new kind needs a
+// field in Action, an entry here and a case in the Launcher.
+var actionKinds = []struct {
+	key   string
+	value func(a *Action) *string
+}{
+	{"shellExecute", func(a *Action) *string { return &a.ShellExecute }},
+	{"createProcess", func(a *Action) *string { return &a.CreateProcess }},
+	{"openProcess", func(a *Action) *string { return &a.OpenProcess }},
+	{"command", func(a *Action) *string { return &a.Command }},
+}
+
+// Launch is an Action reduced to what the Launcher needs
+type Launch struct {
+	Kind          string // key of actionKinds
+	File          string // program, file or command name
+	Args          []string
+	CommandLine   string
+	Hidden        bool
+	Workdir       string
+	Env           map[string]string
+	Show          string
+	Verb          string
+	FocusExisting *FocusExisting
+}
+
+// Parameters returns the arguments as they go to the command line, after
+// the program
+func (l Launch) Parameters() string {
+	if l.CommandLine != "" {
+		return l.CommandLine
+	}
+	return JoinArgs(l.Args)
+}
+
+// NeedsShell is true for the verbs like "runas" that only ShellExecute knows
+func (l Launch) NeedsShell() bool {
+	return l.Verb != "" && !strings.EqualFold(l.Verb, "open")
+}
+
+// Launcher executes a Launch. The one of the shell starts programs and runs
+// the commands, a fake can record the launches instead.
+type Launcher interface {
+	Launch(l Launch) error
+}
+
+// Launch returns what to launch, ok is false if the action is empty
+func (a *Action) Launch() (l Launch, ok bool) {
+	for _, kind := range actionKinds {
+		if v := *kind.value(a); v != "" {
+			return Launch{
+				Kind:          kind.key,
+				File:          v,
+				Args:          a.Args,
+				CommandLine:   a.CommandLine,
+				Hidden:        a.Hidden,
+				Workdir:       a.Workdir,
+				Env:           a.Env,
+				Show:          a.Show,
+				Verb:          a.Verb,
+				FocusExisting: a.FocusExisting,
+			}, true
+		}
+	}
+	return Launch{}, false
+}
+
+// File returns the program or file of the action, for the icon
+func (a *Action) File() string {
+	if l, ok := a.Launch(); ok && l.Kind != "command" {
+		return l.File
+	}
+	return ""
+}
+
+// Run launches the action with the launcher, an empty action does nothing
+func (a *Action) Run(launcher Launcher) error {
+	l, ok := a.Launch()
+	if !ok {
+		return nil
+	}
+	if err := launcher.Launch(l); err != nil {
+		return fmt.Errorf("%s %q: %w", l.Kind, l.File, err)
+	}
+	return nil
+}
+
+// expand applies fn to every string of the action that can contain variables
+func (a *Action) expand(fn func(s *string), all func(list []string) []string) {
+	for _, kind := range actionKinds {
+		if kind.key != "command" {
+			fn(kind.value(a))
+		}
+	}
+	a.Args = all(a.Args)
+	fn(&a.CommandLine)
+	fn(&a.Workdir)
+	a.Env = expandEnv(a.Env, fn)
+}
+
+// expandEnv applies fn to the values of env
+func expandEnv(env map[string]string, fn func(s *string)) map[string]string {
+	if env == nil {
+		return nil
+	}
+	// a new map, the old one can be shared with a merged config
+	result := make(map[string]string, len(env))
+	for k, v := range env {
+		fn(&v)
+		result[k] = v
+	}
+	return result
+}
+
+// MergeEnv returns environ ("key=value" like os.Environ) with env applied,
+// the keys are case insensitive like on Windows
+func MergeEnv(environ []string, env map[string]string) []string {
+	result := make([]string, 0, len(environ)+len(env))
+	done := make(map[string]bool, len(env))
+	for _, kv := range environ {
+		key := kv
+		i := strings.Index(kv, "=")
+		if i == 0 { // hidden variables like "=C:=C:\" start with "="
+			i = strings.Index(kv[1:], "=") + 1
+		}
+		if i > 0 {
+			key = kv[:i]
+		}
+		if name, ok := lookupEnvKey(env, key); ok {
+			if !done[name] && env[name] != "" {
+				result = append(result, key+"="+env[name])
+			}
+			done[name] = true
+			continue
+		}
+		result = append(result, kv)
+	}
+
+	var added []string
+	for k, v := range env {
+		if !done[k] && v != "" {
+			added = append(added, k+"="+v)
+		}
+	}
+	sort.Strings(added)
+	return append(result, added...)
+}
+
+func lookupEnvKey(env map[string]string, key string) (string, bool) {
+	for k := range env {
+		if strings.EqualFold(k, key) {
+			return k, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// recordingLauncher is a Launcher that only remembers the launches
+type recordingLauncher struct {
+	launches []Launch
+	err      error
+}
+
+func (r *recordingLauncher) Launch(l Launch) error {
+	r.launches = append(r.launches, l)
+	return r.err
+}
+
+func TestActionRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		action Action
+		want   []Launch
+	}{
+		{"empty", Action{}, nil},
+		{"shellExecute", Action{ShellExecute: "notepad.exe", Args: []string{"a b.txt"}, Show: "maximized"},
+			[]Launch{{Kind: "shellExecute", File: "notepad.exe", Args: []string{"a b.txt"}, Show: "maximized"}}},
+		{"createProcess", Action{CreateProcess: "cmd.exe", CommandLine: `/c "dir"`, Hidden: true, Workdir: "C:"},
+			[]Launch{{Kind: "createProcess", File: "cmd.exe", CommandLine: `/c "dir"`, Hidden: true, Workdir: "C:"}}},
+		{"openProcess", Action{OpenProcess: "py.exe", Env: map[string]string{"A": "1"}, Verb: "runas"},
+			[]Launch{{Kind: "openProcess", File: "py.exe", Env: map[string]string{"A": "1"}, Verb: "runas"}}},
+		{"command", Action{Command: "!toggleTaskbar", Args: []string{"hide"}},
+			[]Launch{{Kind: "command", File: "!toggleTaskbar", Args: []string{"hide"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := new(recordingLauncher)
+			if err := tt.action.Run(l); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(l.launches, tt.want) {
+				t.Errorf("got %+v, want %+v", l.launches, tt.want)
+			}
+		})
+	}
+}
+
+func TestActionRunError(t *testing.T) {
+	l := &recordingLauncher{err: errors.New("not found")}
+	a := Action{OpenProcess: "missing.exe"}
+	err := a.Run(l)
+	if want := `openProcess "missing.exe": not found`; err == nil || err.Error() != want {
+		t.Errorf("got %v, want %s", err, want)
+	}
+	if !errors.Is(err, l.err) {
+		t.Errorf("%v doesn't wrap the error of the launcher", err)
+	}
+}
+
+func TestActionFile(t *testing.T) {
+	for _, tt := range []struct {
+		action Action
+		want   string
+	}{
+		{Action{}, ""},
+		{Action{ShellExecute: "a.txt"}, "a.txt"},
+		{Action{OpenProcess: "b.exe"}, "b.exe"},
+		{Action{Command: "!reload"}, ""},
+	} {
+		if got := tt.action.File(); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.action, got, tt.want)
+		}
+	}
+}
+
+func TestLaunchParameters(t *testing.T) {
+	for _, tt := range []struct {
+		launch Launch
+		want   string
+		shell  bool
+	}{
+		{Launch{Args: []string{"/t", "0"}}, "/t 0", false},
+		{Launch{Args: []string{"a b", ""}, CommandLine: "as is"}, "as is", false},
+		{Launch{Args: []string{"a b", ""}, Verb: "open"}, `"a b" ""`, false},
+		{Launch{Verb: "runas"}, "", true},
+	} {
+		if got := tt.launch.Parameters(); got != tt.want {
+			t.Errorf("Parameters of %+v: got %q, want %q", tt.launch, got, tt.want)
+		}
+		if got := tt.launch.NeedsShell(); got != tt.shell {
+			t.Errorf("NeedsShell of %+v: got %v", tt.launch, got)
+		}
+	}
+}
+
+func TestFileAction(t *testing.T) {
+	handlers := []FileHandler{
+		{Match: ".txt", Action: Action{OpenProcess: "notepad.exe", Args: []string{"{path}"}, Workdir: "{dir}"}},
+	}
+	for _, tt := range []struct {
+		file string
+		want Launch
+	}{
+		{"dir/a.TXT", Launch{Kind: "openProcess", File: "notepad.exe", Args: []string{"dir/a.TXT"}, Workdir: "dir"}},
+		{"dir/b.ps1", Launch{Kind: "openProcess", File: "PowerShell.exe",
+			Args:    []string{"-NoLogo", "-NoProfile", "-ExecutionPolicy", "Bypass", "-File", "dir/b.ps1"},
+			Workdir: "dir"}},
+		{"dir/c.jpg", Launch{Kind: "openProcess", File: "rundll32.exe", Args: []string{"url.dll,FileProtocolHandler", "dir/c.jpg"}}},
+	} {
+		l := new(recordingLauncher)
+		a := FileAction(handlers, tt.file)
+		if err := a.Run(l); err != nil {
+			t.Fatal(err)
+		}
+		if len(l.launches) != 1 || !reflect.DeepEqual(l.launches[0], tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.file, l.launches, tt.want)
+		}
+	}
+	// the placeholders of the handler stay for the next file
+	if got := handlers[0].Args[0]; got != "{path}" {
+		t.Errorf("the handler was changed to %q", got)
+	}
+}
+
+func TestMergeEnv(t *testing.T) {
+	environ := []string{"=C:=C:\\", "Path=C:\\Windows", "TEMP=C:\\Temp", "USERNAME=a"}
+	env := map[string]string{"PATH": "D:\\bin", "temp": "", "NEW": "1"}
+	want := []string{"=C:=C:\\", "Path=D:\\bin", "USERNAME=a", "NEW=1"}
+	if got := MergeEnv(environ, env); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseColor(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want Color
+	}{
+		{"#102030", Color{R: 0x10, G: 0x20, B: 0x30, A: 255}},
+		{"#A0b0C0", Color{R: 0xa0, G: 0xb0, B: 0xc0, A: 255}},
+		{"#10203040", Color{R: 0x10, G: 0x20, B: 0x30, A: 0x40}},
+		{"  #ffffff  ", Color{R: 255, G: 255, B: 255, A: 255}},
+		{"rgb(1, 2, 3)", Color{R: 1, G: 2, B: 3, A: 255}},
+		{"RGB(255,0,0)", Color{R: 255, A: 255}},
+		{"rgb(100%, 50%, 0%)", Color{R: 255, G: 128, B: 0, A: 255}},
+		{"rgba(1, 2, 3, 0.5)", Color{R: 1, G: 2, B: 3, A: 128}},
+		{"rgba(1, 2, 3, 0)", Color{R: 1, G: 2, B: 3, A: 0}},
+		{"rgba(1, 2, 3, 1)", Color{R: 1, G: 2, B: 3, A: 255}},
+		{"rgba(1, 2, 3, 25%)", Color{R: 1, G: 2, B: 3, A: 64}},
+		{"rgb(1, 2, 3, 0.5)", Color{R: 1, G: 2, B: 3, A: 128}}, // like CSS 4
+		{"red", Color{R: 255, A: 255}},
+		{"RebeccaPurple", Color{R: 102, G: 51, B: 153, A: 255}},
+		{"transparent", Color{}},
+	} {
+		got, err := ParseColor(tt.s)
+		if err != nil || got != tt.want {
+			t.Errorf("%q: got %+v, %v, want %+v", tt.s, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseColorErrors(t *testing.T) {
+	for _, tt := range []struct {
+		s, err string
+	}{
+		{"#12345", `invalid color "#12345", expected #RRGGBB or #RRGGBBAA`},
+		{"#1234567", `invalid color "#1234567", expected #RRGGBB or #RRGGBBAA`},
+		{"#", `invalid color "#", expected #RRGGBB or #RRGGBBAA`},
+		{"#12345g", `invalid color "#12345g", "12345g" is not a hex number`},
+		{"rgb(1, 2, 3", `invalid color "rgb(1, 2, 3", missing ")"`},
+		{"rgb(1, 2)", `invalid color "rgb(1, 2)", expected rgb(r, g, b) or rgba(r, g, b, a)`},
+		{"rgb(1, 2, 256)", `invalid color "rgb(1, 2, 256)", "256" is not a number from 0 to 255`},
+		{"rgb(-1, 2, 3)", `invalid color "rgb(-1, 2, 3)", "-1" is not a number from 0 to 255`},
+		{"rgb(1, x, 3)", `invalid color "rgb(1, x, 3)", "x" is not a number from 0 to 255`},
+		{"rgb(101%, 2, 3)", `invalid color "rgb(101%, 2, 3)", "101%" is not a percentage from 0% to 100%`},
+		{"rgba(1, 2, 3, 1.5)", `invalid color "rgba(1, 2, 3, 1.5)", alpha "1.5" is not a number from 0 to 1`},
+		{"rgba(1, 2, 3, 200%)", `invalid color "rgba(1, 2, 3, 200%)", "200%" is not a percentage from 0% to 100%`},
+		{"bluish", `invalid color "bluish", expected #RRGGBB, #RRGGBBAA, rgb(r, g, b) or a CSS color name`},
+		{"", `invalid color "", expected #RRGGBB, #RRGGBBAA, rgb(r, g, b) or a CSS color name`},
+	} {
+		_, err := ParseColor(tt.s)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%q: got %v, want %s", tt.s, err, tt.err)
+		}
+	}
+}
+
+func TestColorUnmarshalYAML(t *testing.T) {
+	for _, tt := range []struct {
+		doc  string
+		want Color
+		err  string
+	}{
+		{"{r: 1, g: 2, b: 3}", Color{R: 1, G: 2, B: 3, A: 255, set: true}, ""},
+		{"{r: 1, g: 2, b: 3, a: 0}", Color{R: 1, G: 2, B: 3, set: true}, ""},
+		{"{}", Color{A: 255, set: true}, ""},
+		{`"#010203"`, Color{R: 1, G: 2, B: 3, A: 255, set: true}, ""},
+		{"navy", Color{B: 128, A: 255, set: true}, ""},
+		{"{r: 256, g: 0, b: 0}", Color{},
+			`yaml: unmarshal errors:` + "\n  " + `line 1: invalid color value r: "256", expected a number from 0 to 255`},
+		{"{r: -1}", Color{},
+			`yaml: unmarshal errors:` + "\n  " + `line 1: invalid color value r: "-1", expected a number from 0 to 255`},
+		{"{red: 1}", Color{},
+			`yaml: unmarshal errors:` + "\n  " + `line 1: unknown color key "red", possible keys: r, g, b, a`},
+		{"[1, 2, 3]", Color{},
+			`yaml: unmarshal errors:` + "\n  " + `line 1: expected a mapping {r, g, b} or a string like "#RRGGBB"`},
+		{"\n#comment\nbluish", Color{},
+			`yaml: unmarshal errors:` + "\n  " + `line 3: invalid color "bluish", expected #RRGGBB, #RRGGBBAA, rgb(r, g, b) or a CSS color name`},
+	} {
+		var c Color
+		err := yaml.Unmarshal([]byte(tt.doc), &c)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%q: got %v, want %s", tt.doc, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || c != tt.want {
+			t.Errorf("%q: got %+v, %v, want %+v", tt.doc, c, err, tt.want)
+		}
+	}
+}
+
+// String writes the shortest form that ParseColor reads back
+func TestColorString(t *testing.T) {
+	for _, tt := range []struct {
+		c    Color
+		want string
+	}{
+		{Color{R: 1, G: 2, B: 255, A: 255}, "#0102ff"},
+		{Color{R: 1, G: 2, B: 3, A: 0}, "#01020300"},
+	} {
+		if got := tt.c.String(); got != tt.want {
+			t.Errorf("%+v: got %s, want %s", tt.c, got, tt.want)
+		}
+		if back, err := ParseColor(tt.want); err != nil || back != tt.c {
+			t.Errorf("%s: read back %+v, %v", tt.want, back, err)
+		}
+	}
+}
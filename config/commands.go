@@ -0,0 +1,42 @@
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// Commands are the built-in commands for the "command" action, like "!reload"
+// in the spirit of the LiteStep bangs, with a description of their arguments
+// for the error messages. GoShell runs them, see commands.go there.
+var Commands = map[string]string{
+	"!reload":          "",
+	"!quit":            "",
+	"!toggleTaskbar":   "[show|hide]",
+	"!showDesktopMenu": "",
+	"!showTaskMenu":    "",
+	"!minimizeAll":     "",
+	"!restoreAll":      "",
+	"!lock":            "",
+	"!logoff":          "[force]",
+}
+
+// LookupCommand returns the name of a built-in command as it is written in
+// Commands, the names are compared case insensitive
+func LookupCommand(name string) (string, bool) {
+	for n := range Commands {
+		if strings.EqualFold(n, name) {
+			return n, true
+		}
+	}
+	return "", false
+}
+
+// CommandNames returns the names for error messages
+func CommandNames() []string {
+	names := make([]string, 0, len(Commands))
+	for name := range Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
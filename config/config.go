@@ -0,0 +1,409 @@
+// Package config reads, checks and merges the config.yaml of GoShell. It
+// has no dependencies on Windows, so the config can be checked and tested
+// on any system.
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	// version of the config file, see migrate.go
+	Version int `yaml:"version,omitempty"`
+
+	Desktop struct {
+		Contextmenu struct {
+			DarkMode      bool `yaml:"darkMode"`
+			AddDebugEntry bool `yaml:"addDebugEntry"`
+		} `yaml:"contextmenu"`
+	} `yaml:"desktop"`
+	Taskbar struct {
+		FontFamily   string `yaml:"fontFamily"`
+		FontSize     int    `yaml:"fontSize"`
+		Position     string `yaml:"position"`
+		IconPosition string `yaml:"iconPosition"`
+		Height       int    `yaml:"height"`
+		Button       struct {
+			Size struct {
+				Width  int `yaml:"width"`
+				Height int `yaml:"height"`
+			} `yaml:"size"`
+			Bgcolor   Color `yaml:"bgcolor"`
+			Textcolor Color `yaml:"textcolor"`
+		} `yaml:"button"`
+		Bgcolor Color `yaml:"bgcolor"`
+		// right click on the taskbar, Taskmgr if empty
+		Contextmenu []Contextmenu `yaml:"contextmenu,omitempty"`
+	} `yaml:"taskbar"`
+	Contextmenu []Contextmenu `yaml:"contextmenu"`
+	Hotkey      []Hotkey      `yaml:"hotkey"`
+	// programs that run as long as the shell
+	Services []Service `yaml:"services,omitempty"`
+	// how the files of the folder menus, the startup and the ones dropped on
+	// the desktop are opened, before defaultFileHandlers
+	FileHandlers []FileHandler `yaml:"fileHandlers,omitempty"`
+	// order and delays of the programs started with -startup
+	Startup StartupConfig `yaml:"startup"`
+
+	// other config files that are merged in order, this file is merged last
+	Include []string `yaml:"include,omitempty"`
+	// parts of the config that only apply to a computer or user
+	Overlay struct {
+		Host map[string]Config `yaml:"host,omitempty"`
+		User map[string]Config `yaml:"user,omitempty"`
+	} `yaml:"overlay,omitempty"`
+
+	// user defined variables for ${name} or %name%
+	Variables map[string]string `yaml:"variables,omitempty"`
+
+	// keys like "taskbar.position" that a user config can't change,
+	// only used in the config of the computer (%ProgramData%)
+	Lock []string `yaml:"lock,omitempty"`
+
+	// every file the config was read from
+	files []string
+	// the paths of the keys that are written in the files, like
+	// "taskbar.height", nil for a config that wasn't read from a file
+	set map[string]bool
+	// paths of the values that were set by setDefaults
+	defaults []string
+	// files with an older version that were migrated while reading
+	migrated []string
+}
+
+// Files returns every file the config was read from
+func (c *Config) Files() []string { return c.files }
+
+// Migrated returns the files with an older version, they were migrated
+// while reading but not written back
+func (c *Config) Migrated() []string { return c.migrated }
+
+type Contextmenu struct {
+	Name string   `yaml:"name"`
+	Path []string `yaml:"path,omitempty"`
+	Icon struct {
+		Filename string `yaml:"filename"`
+		Index    int    `yaml:"index"`
+	} `yaml:"icon,omitempty"`
+	// a submenu with the recently launched items
+	Recent *RecentMenu `yaml:"recent,omitempty"`
+	// how the files of path are shown
+	FolderOptions `yaml:",inline"`
+	Action        `yaml:",inline"`
+	MergeRule     `yaml:",inline"`
+}
+
+// RecentMenu is a contextmenu entry with the recently launched items
+type RecentMenu struct {
+	Size int `yaml:"size,omitempty"` // items without the pinned ones, 0 is RecentDefaultSize
+}
+
+const RecentDefaultSize = 10
+
+// SizeOrDefault is Size, RecentDefaultSize if it is not set
+func (r *RecentMenu) SizeOrDefault() int {
+	if r.Size <= 0 {
+		return RecentDefaultSize
+	}
+	return r.Size
+}
+
+type Hotkey struct {
+	Buttons   string `yaml:"buttons"`
+	Action    `yaml:",inline"`
+	MergeRule `yaml:",inline"`
+}
+
+// FindConfig returns the config file to use: the one of the -config flag,
+// otherwise the first config.yaml that exists in the profile of the user,
+// for the computer and in exDir, the folder of the executable.
+func FindConfig(flagPath, exDir string) string {
+	if flagPath != "" {
+		if abs, err := filepath.Abs(flagPath); err == nil {
+			return abs
+		}
+		return flagPath
+	}
+	for _, file := range []string{UserConfigPath(), MachineConfigPath()} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return filepath.Join(exDir, "config.yaml")
+}
+
+// UserConfigPath is %APPDATA%\GoShell\config.yaml
+func UserConfigPath() string {
+	dir := os.Getenv("APPDATA")
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "GoShell", "config.yaml")
+}
+
+// MachineConfigPath is %ProgramData%\GoShell\config.yaml, its lock list
+// applies to every other config
+func MachineConfigPath() string {
+	dir := os.Getenv("ProgramData")
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "GoShell", "config.yaml")
+}
+
+// ResolveVariables expands the environment variables and known folders
+// of strings that don't come from the config, like registry values.
+func ResolveVariables(data string) string {
+	data, _ = ExpandVariables(data, LookupVariable)
+	return data
+}
+
+// KnownFolder returns the path of a known folder like "FOLDERID_Desktop",
+// GoShell sets it to the one of Windows. Without it the known folders are
+// unknown variables.
+var KnownFolder func(name string) (string, bool)
+
+// LookupVariable resolves the known folders and the environment variables
+func LookupVariable(name string) (string, bool) {
+	if strings.HasPrefix(name, "FOLDERID_") {
+		if KnownFolder == nil {
+			return "", false
+		}
+		return KnownFolder(name)
+	}
+	return os.LookupEnv(name)
+}
+
+// lookupVariable resolves the variables section before the environment
+func (c *Config) lookupVariable(name string) (string, bool) {
+	return c.lookupVariableDepth(name, 0)
+}
+
+func (c *Config) lookupVariableDepth(name string, depth int) (string, bool) {
+	for k, v := range c.Variables {
+		if !strings.EqualFold(k, name) {
+			continue
+		}
+		if depth > 8 {
+			log.Printf("config: variable %q refers to itself\n", name)
+			return v, true
+		}
+		// variables can use other variables
+		v, _ = ExpandVariables(v, func(name string) (string, bool) {
+			return c.lookupVariableDepth(name, depth+1)
+		})
+		return v, true
+	}
+	return LookupVariable(name)
+}
+
+// expandVariables expands the variables of every path, file name and
+// argument of the config
+func expandVariables(c *Config) {
+	expand := func(s *string) {
+		var warnings []string
+		*s, warnings = ExpandVariables(*s, c.lookupVariable)
+		for _, w := range warnings {
+			log.Printf("config: %s in %q\n", w, *s)
+		}
+	}
+	expandAll := func(list []string) []string {
+		// the slices may be shared with other configs after a merge
+		list = append([]string(nil), list...)
+		for i := range list {
+			expand(&list[i])
+		}
+		return list
+	}
+
+	for _, menu := range [][]Contextmenu{c.Contextmenu, c.Taskbar.Contextmenu} {
+		for i := range menu {
+			m := &menu[i]
+			m.Path = expandAll(m.Path)
+			expand(&m.Icon.Filename)
+			m.Action.expand(expand, expandAll)
+		}
+	}
+	for i := range c.Hotkey {
+		c.Hotkey[i].Action.expand(expand, expandAll)
+	}
+	for i := range c.FileHandlers {
+		c.FileHandlers[i].Action.expand(expand, expandAll)
+	}
+	for i := range c.Services {
+		svc := &c.Services[i]
+		expand(&svc.Program)
+		svc.Args = expandAll(svc.Args)
+		expand(&svc.CommandLine)
+		expand(&svc.Workdir)
+		svc.Env = expandEnv(svc.Env, expand)
+	}
+}
+
+// ReadConfig parses the given file together with its includes and overlays,
+// restores the keys locked by the config of the computer and applies the
+// default values. The returned config is never nil, even when an error is
+// returned.
+func ReadConfig(file string) (*Config, error) {
+	c, errs := readConfig(file)
+
+	if machine := MachineConfigPath(); machine != "" && !strings.EqualFold(machine, file) {
+		if _, err := os.Stat(machine); err == nil {
+			m, machineErrs := readConfig(machine)
+			c = ApplyLocks(c, m)
+			c.files = append(c.files, m.files...)
+			c.migrated = append(c.migrated, m.migrated...)
+			errs = append(errs, machineErrs...)
+		}
+	}
+	setDefaults(c)
+
+	if len(errs) != 0 {
+		return c, errs
+	}
+	return c, nil
+}
+
+func readConfig(file string) (*Config, ConfigErrors) {
+	l := new(configLoader)
+	c := l.load(file)
+
+	hostname, _ := os.Hostname()
+	c, errs := ApplyOverlays(c, hostname, os.Getenv("USERNAME"))
+	c.files = l.files
+	c.migrated = l.migrated
+	expandVariables(c)
+	return c, append(l.errs, errs...)
+}
+
+type configLoader struct {
+	files    []string // every file that was read
+	migrated []string // files with an older version
+	stack    []string // to detect include loops
+	errs     ConfigErrors
+}
+
+// load decodes a single file and merges its includes below it
+func (l *configLoader) load(file string) *Config {
+	c := new(Config)
+	for _, f := range l.stack {
+		if strings.EqualFold(f, file) {
+			l.errs = append(l.errs, ConfigError{File: l.stack[len(l.stack)-1], Msg: fmt.Sprintf("include loop: %s", file)})
+			return c
+		}
+	}
+	l.files = append(l.files, file)
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		l.errs = append(l.errs, ConfigError{File: file, Msg: err.Error()})
+		return c
+	}
+	f, errs := newConfigFile(file, content)
+	if errs == nil {
+		if from, err := MigrateConfig(f); err != nil {
+			errs = ConfigErrors{{Msg: err.Error()}}
+		} else if from < configVersion {
+			l.migrated = append(l.migrated, file)
+		}
+		errs = append(errs, validateDocument(f.root)...)
+		f.root.Decode(c) // the problems are reported by validateDocument
+		if len(f.root.Content) != 0 {
+			setMergePositions(reflect.ValueOf(c).Elem(), f.root.Content[0], file)
+			c.set = keyPaths(f.root.Content[0], "", map[string]bool{})
+		}
+	}
+	for _, e := range errs {
+		e.File = file
+		l.errs = append(l.errs, e)
+	}
+
+	if len(c.Include) == 0 {
+		return c
+	}
+	l.stack = append(l.stack, file)
+	base := new(Config)
+	for _, inc := range c.Include {
+		inc = ResolveVariables(inc) // without the variables section, it isn't merged yet
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(filepath.Dir(file), inc)
+		}
+		base = l.merge(base, l.load(inc))
+	}
+	l.stack = l.stack[:len(l.stack)-1]
+
+	c.Include = nil
+	return l.merge(base, c)
+}
+
+// merge is MergeConfig that collects the errors
+func (l *configLoader) merge(base, overlay *Config) *Config {
+	c, errs := MergeConfig(base, overlay)
+	l.errs = append(l.errs, errs...)
+	return c
+}
+
+// setDefaults fills in the values that are not set, their paths are kept
+// in c.defaults for -print-config
+func setDefaults(c *Config) {
+	c.defaults = nil
+	setInt := func(path string, v *int, def int) {
+		if *v == 0 {
+			*v = def
+			c.defaults = append(c.defaults, path)
+		}
+	}
+
+	setInt("taskbar.height", &c.Taskbar.Height, 30)
+
+	if c.Taskbar.FontFamily == "" {
+		c.Taskbar.FontFamily = "Segoe UI"
+		c.defaults = append(c.defaults, "taskbar.fontFamily")
+	}
+	setInt("taskbar.fontSize", &c.Taskbar.FontSize, 9)
+
+	setInt("taskbar.button.size.width", &c.Taskbar.Button.Size.Width, 160)
+	setInt("taskbar.button.size.height", &c.Taskbar.Button.Size.Height, 30)
+
+	setInt("startup.parallel", &c.Startup.Parallel, 2)
+	if c.Startup.Settle == 0 {
+		c.Startup.Settle = Duration(3 * time.Second)
+		c.defaults = append(c.defaults, "startup.settle")
+	}
+
+	if c.Taskbar.Position == "" {
+		c.Taskbar.Position = "bottom"
+		c.defaults = append(c.defaults, "taskbar.position")
+	}
+	if c.Taskbar.IconPosition == "" {
+		c.Taskbar.IconPosition = "left"
+		c.defaults = append(c.defaults, "taskbar.iconPosition")
+	}
+
+	// black like before there was a Color type, only for the colors that are
+	// missing in the config
+	for path, color := range map[string]*Color{
+		"taskbar.bgcolor":          &c.Taskbar.Bgcolor,
+		"taskbar.button.bgcolor":   &c.Taskbar.Button.Bgcolor,
+		"taskbar.button.textcolor": &c.Taskbar.Button.Textcolor,
+	} {
+		if !color.set {
+			*color = Color{A: 255}
+			c.defaults = append(c.defaults, path)
+		}
+	}
+	sort.Strings(c.defaults)
+
+	c.Taskbar.IconPosition = strings.ToLower(c.Taskbar.IconPosition)
+	c.Taskbar.Position = strings.ToLower(c.Taskbar.Position)
+}
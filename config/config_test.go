@@ -0,0 +1,59 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSetDefaultsColors(t *testing.T) {
+	file := writeConfig(t, "config.yaml", `
+version: 2
+taskbar:
+  bgcolor: transparent
+  button:
+    bgcolor: rgba(0, 0, 0, 0)
+`)
+	c, err := ReadConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		path string
+		got  Color
+		want string
+	}{
+		{"taskbar.bgcolor", c.Taskbar.Bgcolor, "#00000000"},
+		{"taskbar.button.bgcolor", c.Taskbar.Button.Bgcolor, "#00000000"},
+		{"taskbar.button.textcolor", c.Taskbar.Button.Textcolor, "#000000"},
+	} {
+		if got := tt.got.String(); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.path, got, tt.want)
+		}
+	}
+
+	var colors []string
+	for _, path := range c.defaults {
+		if path == "taskbar.bgcolor" || path == "taskbar.button.bgcolor" || path == "taskbar.button.textcolor" {
+			colors = append(colors, path)
+		}
+	}
+	if want := []string{"taskbar.button.textcolor"}; !reflect.DeepEqual(colors, want) {
+		t.Errorf("defaults: got %q, want %q", colors, want)
+	}
+}
+
+// an overlay can make a color of the base transparent
+func TestMergeTransparentColor(t *testing.T) {
+	var base, overlay Config
+	if err := yaml.Unmarshal([]byte("taskbar: {bgcolor: \"#202020\"}"), &base); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal([]byte("taskbar: {bgcolor: transparent}"), &overlay); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := MergeConfig(&base, &overlay); c.Taskbar.Bgcolor.A != 0 {
+		t.Errorf("got %v, want transparent", c.Taskbar.Bgcolor)
+	}
+}
@@ -0,0 +1,562 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFile changes a config file in place. Every change is made in the
+// node tree and as edit of the text itself, so comments, the order of the
+// keys and the formatting stay as they are. Only if a change can't be made
+// in the text (flow style, multi line values, Edit) the file is written from
+// the node tree, which keeps the comments and the order but not every
+// detail of the formatting.
+type ConfigFile struct {
+	path    string
+	content []byte
+	root    *yaml.Node
+
+	edits      []textEdit
+	structural bool
+	// scalars whose text is replaced by edits[i]
+	replaced map[*yaml.Node]int
+}
+
+// replaces content[start:end] with text
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// OpenConfigFile reads a config file for changes
+func OpenConfigFile(path string) (*ConfigFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, errs := newConfigFile(path, content)
+	if errs != nil {
+		errs[0].File = path
+		return nil, errs
+	}
+	return f, nil
+}
+
+func newConfigFile(path string, content []byte) (*ConfigFile, ConfigErrors) {
+	root, errs := parseConfig(content)
+	if errs != nil {
+		return nil, errs
+	}
+	if len(root.Content) == 0 {
+		root.Kind = yaml.DocumentNode
+		root.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	return &ConfigFile{path: path, content: content, root: root}, nil
+}
+
+// SetConfigValue changes a single key of a config file, e.g.
+// SetConfigValue(configPath, "taskbar.position", "top")
+func SetConfigValue(file, path string, value interface{}) error {
+	f, err := OpenConfigFile(file)
+	if err != nil {
+		return err
+	}
+	if err := f.Set(path, value); err != nil {
+		return err
+	}
+	return f.Save()
+}
+
+// Set changes the value of path like "taskbar.position" or
+// "contextmenu[2].hidden", missing keys are added.
+func (f *ConfigFile) Set(path string, value interface{}) error {
+	n := new(yaml.Node)
+	if err := n.Encode(value); err != nil {
+		return err
+	}
+	mapping, keys, err := f.lookup(path)
+	if err != nil {
+		return err
+	}
+	// the missing mappings are added together with the value
+	for i := len(keys) - 1; i > 0; i-- {
+		n = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: keys[i]}, n,
+		}}
+	}
+	f.setValue(mapping, keys[0], n, false)
+	return nil
+}
+
+// Delete removes the key of path, it's no error if it doesn't exist
+func (f *ConfigFile) Delete(path string) error {
+	mapping, keys, err := f.lookup(path)
+	if err != nil || len(keys) != 1 {
+		return err
+	}
+	f.deleteKey(mapping, keys[0])
+	return nil
+}
+
+// Edit changes the node tree directly, the file is written from the tree
+func (f *ConfigFile) Edit(fn func(root *yaml.Node) error) error {
+	f.structural = true
+	return fn(f.root)
+}
+
+// Save writes the changes atomically: into a temporary file that replaces
+// the config afterwards, so a crash never leaves half a config behind.
+func (f *ConfigFile) Save() error {
+	content, err := f.Bytes()
+	if err != nil || bytes.Equal(content, f.content) {
+		return err
+	}
+	if err := WriteFileAtomic(f.path, content); err != nil {
+		return err
+	}
+	f.content, f.edits, f.structural, f.replaced = content, nil, false, nil
+	return nil
+}
+
+// Bytes returns the changed file
+func (f *ConfigFile) Bytes() ([]byte, error) {
+	// from the end, so the offsets of the other edits stay valid, keys
+	// that are added at the same place keep their order
+	edits := make([]textEdit, len(f.edits))
+	for i := range f.edits {
+		edits[len(edits)-1-i] = f.edits[i]
+	}
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	for i := 1; i < len(edits); i++ {
+		if edits[i].end > edits[i-1].start {
+			f.structural = true // overlapping edits
+		}
+	}
+
+	if f.structural {
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(detectIndent(f.content))
+		if err := enc.Encode(f.root); err != nil {
+			return nil, err
+		}
+		enc.Close()
+		return buf.Bytes(), nil
+	}
+
+	content := append([]byte(nil), f.content...)
+	for _, e := range edits {
+		content = append(content[:e.start], append([]byte(e.text), content[e.end:]...)...)
+	}
+	return content, nil
+}
+
+// setValue sets key of mapping to n, first adds a missing key as first key
+// instead of the last one
+func (f *ConfigFile) setValue(mapping *yaml.Node, key string, n *yaml.Node, first bool) {
+	old := valueNode(mapping, key)
+	if old == nil {
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+		f.insertEdit(mapping, keyNode, n, first)
+		if first {
+			mapping.Content = append([]*yaml.Node{keyNode, n}, mapping.Content...)
+		} else {
+			mapping.Content = append(mapping.Content, keyNode, n)
+		}
+		return
+	}
+
+	if old.Kind == yaml.ScalarNode && n.Kind == yaml.ScalarNode {
+		if old.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 && n.Tag == "!!str" {
+			n.Style = old.Style
+		}
+		f.scalarEdit(old, n)
+	} else {
+		f.structural = true
+	}
+	// the position stays for the next edits, the text is still on the same line
+	n.Line, n.Column = old.Line, old.Column
+	n.HeadComment, n.LineComment, n.FootComment = old.HeadComment, old.LineComment, old.FootComment
+	*old = *n
+}
+
+// deleteKey removes key and its value from mapping
+func (f *ConfigFile) deleteKey(mapping *yaml.Node, key string) bool {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			f.deleteEdit(mapping, mapping.Content[i], mapping.Content[i+1])
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// setScalar changes the text of the scalar n, a quoted style stays
+func (f *ConfigFile) setScalar(n *yaml.Node, value string) {
+	changed := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Style: n.Style & (yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle)}
+	f.scalarEdit(n, changed)
+	n.Value, n.Tag, n.Style = changed.Value, changed.Tag, changed.Style
+}
+
+// replaceItem replaces the item i of a sequence with items
+func (f *ConfigFile) replaceItem(seq *yaml.Node, i int, items []*yaml.Node) {
+	f.replaceItemEdit(seq, seq.Content[i], items)
+	rest := append(items, seq.Content[i+1:]...)
+	seq.Content = append(seq.Content[:i], rest...)
+}
+
+// replaceItemEdit writes the scalars items as "- item" lines in place of
+// the scalar old of a block sequence
+func (f *ConfigFile) replaceItemEdit(seq, old *yaml.Node, items []*yaml.Node) {
+	if f.structural {
+		return
+	}
+	lineStart := offsetOf(f.content, old.Line, 1)
+	start, end, ok := f.scalarRange(old)
+	if seq.Style&yaml.FlowStyle != 0 || old.Kind != yaml.ScalarNode || !ok || lineStart < 0 ||
+		strings.TrimSpace(string(f.content[lineStart:start])) != "-" {
+		f.structural = true
+		return
+	}
+	prefix := string(f.content[lineStart:start]) // the indentation and "- "
+	texts := make([]string, len(items))
+	for i, n := range items {
+		out, err := yaml.Marshal(n)
+		texts[i] = strings.TrimSuffix(string(out), "\n")
+		if err != nil || strings.Contains(texts[i], "\n") {
+			f.structural = true
+			return
+		}
+	}
+	f.edits = append(f.edits, textEdit{start: start, end: end, text: strings.Join(texts, "\n"+prefix)})
+}
+
+// lookup returns the deepest existing mapping on path and the keys of path
+// that are missing below it, the last key is always in the list
+func (f *ConfigFile) lookup(path string) (mapping *yaml.Node, missing []string, err error) {
+	n := f.root.Content[0]
+	keys := strings.Split(path, ".")
+	for i, k := range keys {
+		k, indexes, err := splitIndexes(k)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if n.Kind != yaml.MappingNode {
+			return nil, nil, fmt.Errorf("%s: %s is not a mapping", path, strings.Join(keys[:i], "."))
+		}
+		if i == len(keys)-1 && len(indexes) == 0 {
+			return n, []string{k}, nil
+		}
+
+		next := valueNode(n, k)
+		if next == nil {
+			if len(indexes) != 0 {
+				return nil, nil, fmt.Errorf("%s: %s doesn't exist", path, k)
+			}
+			return n, append([]string{k}, keys[i+1:]...), nil
+		}
+		for _, index := range indexes {
+			if next.Kind != yaml.SequenceNode || index >= len(next.Content) {
+				return nil, nil, fmt.Errorf("%s: %s[%d] doesn't exist", path, k, index)
+			}
+			next = next.Content[index]
+		}
+		if i == len(keys)-1 {
+			return nil, nil, fmt.Errorf("%s: can only set keys of a mapping", path)
+		}
+		n = next
+	}
+	return nil, nil, fmt.Errorf("empty path")
+}
+
+// "contextmenu[2]" -> "contextmenu", [2]
+func splitIndexes(key string) (string, []int, error) {
+	name, rest, _ := strings.Cut(key, "[")
+	if rest == "" {
+		return name, nil, nil
+	}
+	var indexes []int
+	for _, part := range strings.Split("["+rest, "[")[1:] {
+		i, err := strconv.Atoi(strings.TrimSuffix(part, "]"))
+		if err != nil || !strings.HasSuffix(part, "]") || i < 0 {
+			return "", nil, fmt.Errorf("invalid index in %q", key)
+		}
+		indexes = append(indexes, i)
+	}
+	return name, indexes, nil
+}
+
+func valueNode(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalarEdit replaces the text of the scalar old with n
+func (f *ConfigFile) scalarEdit(old, n *yaml.Node) {
+	out, err := yaml.Marshal(n)
+	text := strings.TrimSuffix(string(out), "\n")
+	if err != nil || strings.Contains(text, "\n") {
+		f.structural = true
+		return
+	}
+	if i, ok := f.replaced[old]; ok {
+		f.edits[i].text = text
+		return
+	}
+
+	start, end, ok := f.scalarRange(old)
+	if !ok || bytes.ContainsAny(f.content[start:end], "\r\n") {
+		f.structural = true
+		return
+	}
+	if f.replaced == nil {
+		f.replaced = map[*yaml.Node]int{}
+	}
+	f.replaced[old] = len(f.edits)
+	f.edits = append(f.edits, textEdit{start: start, end: end, text: text})
+}
+
+// insertEdit adds "key: value" as lines of a block mapping
+func (f *ConfigFile) insertEdit(mapping, key, value *yaml.Node, first bool) {
+	if f.structural {
+		return
+	}
+	indent, offset := 0, len(f.content)
+	switch {
+	case len(mapping.Content) == 0 && mapping == f.root.Content[0]:
+		// empty file
+
+	case len(mapping.Content) == 0 || mapping.Style&yaml.FlowStyle != 0 || mapping.Line == 0:
+		f.structural = true
+		return
+
+	case first:
+		// only possible if the first key starts its line
+		indent = mapping.Content[0].Column - 1
+		offset = offsetOf(f.content, mapping.Content[0].Line, 1)
+		if indent != 0 || offset < 0 {
+			f.structural = true
+			return
+		}
+
+	default:
+		indent = mapping.Content[0].Column - 1
+		line, ok := f.lastLine(mapping)
+		if !ok {
+			f.structural = true
+			return
+		}
+		if offset = offsetOf(f.content, line+1, 1); offset < 0 {
+			offset = len(f.content)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(detectIndent(f.content))
+	if err := enc.Encode(&yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{key, value}}); err != nil {
+		f.structural = true
+		return
+	}
+	enc.Close()
+
+	var text strings.Builder
+	if offset == len(f.content) && offset > 0 && f.content[offset-1] != '\n' {
+		text.WriteString("\n")
+	}
+	for _, line := range strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		text.WriteString(strings.Repeat(" ", indent) + line)
+	}
+	text.WriteString("\n")
+	f.edits = append(f.edits, textEdit{start: offset, end: offset, text: text.String()})
+}
+
+// deleteEdit removes the lines of key and its value
+func (f *ConfigFile) deleteEdit(mapping, key, value *yaml.Node) {
+	if f.structural {
+		return
+	}
+	start := offsetOf(f.content, key.Line, 1)
+	keyStart := offsetOf(f.content, key.Line, key.Column)
+	last, ok := f.lastLine(value)
+	if isEmptyScalar(value) {
+		last, ok = key.Line, true
+	}
+	if mapping.Style&yaml.FlowStyle != 0 || key.Line == 0 || !ok || start < 0 || keyStart < 0 ||
+		strings.TrimLeft(string(f.content[start:keyStart]), " ") != "" { // e.g. the first key of "- name: x"
+		f.structural = true
+		return
+	}
+	end := offsetOf(f.content, last+1, 1)
+	if end < 0 {
+		end = len(f.content)
+	}
+	// the comment lines right above belong to the key
+	if key.HeadComment != "" {
+		for line := key.Line - 1; line >= key.Line-1-strings.Count(key.HeadComment, "\n"); line-- {
+			prev := offsetOf(f.content, line, 1)
+			if prev < 0 || !strings.HasPrefix(strings.TrimLeft(string(f.content[prev:start]), " "), "#") {
+				break
+			}
+			start = prev
+		}
+	}
+	f.edits = append(f.edits, textEdit{start: start, end: end})
+}
+
+// lastLine returns the line where the text of n ends
+func (f *ConfigFile) lastLine(n *yaml.Node) (int, bool) {
+	if n.Line == 0 {
+		return 0, false // added by an edit
+	}
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if _, ok := f.replaced[n]; ok {
+			return n.Line, true
+		}
+		if isEmptyScalar(n) {
+			return 0, false // the position of "key:" without value is not the one of the key
+		}
+		start, end, ok := f.scalarRange(n)
+		if !ok {
+			return 0, false
+		}
+		return n.Line + bytes.Count(f.content[start:end], []byte("\n")), true
+	case yaml.MappingNode, yaml.SequenceNode:
+		if n.Style&yaml.FlowStyle != 0 {
+			return 0, false
+		}
+		line := n.Line
+		for _, c := range n.Content {
+			if isEmptyScalar(c) || c.Line == 0 {
+				continue // the line of its key or added by an edit behind it
+			}
+			l, ok := f.lastLine(c)
+			if !ok {
+				return 0, false
+			}
+			if l > line {
+				line = l
+			}
+		}
+		return line, true
+	}
+	return n.Line, true
+}
+
+func isEmptyScalar(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Value == "" && n.Style&^yaml.TaggedStyle == 0
+}
+
+// scalarRange returns the offsets of the text of a scalar
+func (f *ConfigFile) scalarRange(n *yaml.Node) (start, end int, ok bool) {
+	start = offsetOf(f.content, n.Line, n.Column)
+	if n.Line == 0 || start < 0 || start >= len(f.content) {
+		return 0, 0, false
+	}
+	switch {
+	case n.Style&yaml.DoubleQuotedStyle != 0:
+		end = quotedEnd(f.content, start, '"')
+	case n.Style&yaml.SingleQuotedStyle != 0:
+		end = quotedEnd(f.content, start, '\'')
+	case n.Style&^yaml.TaggedStyle == 0 && bytes.HasPrefix(f.content[start:], []byte(n.Value)):
+		end = start + len(n.Value) // a plain scalar on one line
+	default:
+		return 0, 0, false
+	}
+	return start, end, end >= 0
+}
+
+// offsetOf converts the 1-based line and column of yaml.v3 into a byte
+// offset, -1 if it is behind the end
+func offsetOf(content []byte, line, column int) int {
+	offset := 0
+	for l := 1; l < line; l++ {
+		i := bytes.IndexByte(content[offset:], '\n')
+		if i == -1 {
+			return -1
+		}
+		offset += i + 1
+	}
+	for c := 1; c < column; c++ {
+		if offset >= len(content) {
+			return -1
+		}
+		_, size := utf8.DecodeRune(content[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// quotedEnd returns the offset after the closing quote of a quoted scalar
+func quotedEnd(content []byte, start int, quote byte) int {
+	for i := start + 1; i < len(content); i++ {
+		switch {
+		case quote == '"' && content[i] == '\\':
+			i++
+		case quote == '\'' && content[i] == '\'' && i+1 < len(content) && content[i+1] == '\'':
+			i++
+		case content[i] == quote:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// detectIndent returns the indentation of the first indented line, 2 if
+// there is none
+func detectIndent(content []byte) int {
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if indent := len(line) - len(trimmed); indent > 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return indent
+		}
+	}
+	return 2
+}
+
+// WriteFileAtomic writes into a temporary file next to file and renames it,
+// readers see either the old or the new content
+func WriteFileAtomic(file string, content []byte) error {
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(file); err == nil {
+		mode = fi.Mode()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails after the rename
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file) // MoveFileEx with MOVEFILE_REPLACE_EXISTING on Windows
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfigFile = `# GoShell config
+version: 4
+
+taskbar:
+    position: bottom # or top
+    height: 30
+    contextmenu:
+        - name: Taskmgr
+          shellExecute: taskmgr.exe
+          hidden: false
+
+# the desktop
+contextmenu:
+    - name: Explorer
+      openProcess: explorer.exe
+`
+
+// editConfig opens testConfigFile, changes it with edit and returns the text
+func editConfig(t *testing.T, edit func(f *ConfigFile) error) string {
+	t.Helper()
+	f, errs := newConfigFile("config.yaml", []byte(testConfigFile))
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	if err := edit(f); err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := ValidateConfig(got); errs != nil {
+		t.Errorf("the changed config is not valid: %v\n%s", errs, got)
+	}
+	return string(got)
+}
+
+func TestConfigFileSet(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		path  string
+		value interface{}
+		old   string // replaced in testConfigFile by new
+		new   string
+	}{
+		{"existing scalar", "taskbar.position", "top",
+			"    position: bottom # or top\n", "    position: top # or top\n"},
+		{"number", "taskbar.height", 40,
+			"    height: 30\n", "    height: 40\n"},
+		{"missing key", "taskbar.fontSize", 12,
+			"          hidden: false\n", "          hidden: false\n    fontSize: 12\n"},
+		{"missing nested key", "desktop.contextmenu.addDebugEntry", true,
+			"      openProcess: explorer.exe\n", "      openProcess: explorer.exe\ndesktop:\n    contextmenu:\n        addDebugEntry: true\n"},
+		{"indexed path", "taskbar.contextmenu[0].hidden", true,
+			"          hidden: false\n", "          hidden: true\n"},
+		{"indexed path, missing key", "contextmenu[0].hidden", true,
+			"      openProcess: explorer.exe\n", "      openProcess: explorer.exe\n      hidden: true\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := editConfig(t, func(f *ConfigFile) error { return f.Set(tt.path, tt.value) })
+			if want := strings.Replace(testConfigFile, tt.old, tt.new, 1); got != want {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestConfigFileSetErrors(t *testing.T) {
+	for _, path := range []string{
+		"contextmenu[1].hidden",     // no such item
+		"contextmenu[x].hidden",     // not an index
+		"contextmenu[0",             // unclosed
+		"missing[0].name",           // no such list
+		"version.major",             // a scalar
+		"taskbar.contextmenu[0]",    // an item itself
+		"contextmenu[-1].hidden",    // negative
+		"taskbar.contextmenu[0][0]", // not a list
+	} {
+		f, errs := newConfigFile("config.yaml", []byte(testConfigFile))
+		if errs != nil {
+			t.Fatal(errs)
+		}
+		if err := f.Set(path, true); err == nil {
+			t.Errorf("%s: no error", path)
+		}
+	}
+}
+
+func TestSplitIndexes(t *testing.T) {
+	for _, tt := range []struct {
+		key     string
+		name    string
+		indexes []int
+		err     bool
+	}{
+		{"contextmenu", "contextmenu", nil, false},
+		{"contextmenu[2]", "contextmenu", []int{2}, false},
+		{"a[0][10]", "a", []int{0, 10}, false},
+		{"a[]", "", nil, true},
+		{"a[1", "", nil, true},
+		{"a[1]x", "", nil, true},
+		{"a[-1]", "", nil, true},
+	} {
+		name, indexes, err := splitIndexes(tt.key)
+		if name != tt.name || !equalInts(indexes, tt.indexes) || (err != nil) != tt.err {
+			t.Errorf("%s: got %q, %v, %v", tt.key, name, indexes, err)
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestConfigFileDelete(t *testing.T) {
+	// the last key of a mapping, the mapping stays
+	got := editConfig(t, func(f *ConfigFile) error { return f.Delete("taskbar.contextmenu[0].hidden") })
+	if want := strings.Replace(testConfigFile, "          hidden: false\n", "", 1); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// the last key of the file with its comment
+	got = editConfig(t, func(f *ConfigFile) error { return f.Delete("contextmenu") })
+	if want := testConfigFile[:strings.Index(testConfigFile, "\n# the desktop")+1]; got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// a missing key is no error
+	got = editConfig(t, func(f *ConfigFile) error { return f.Delete("taskbar.fontSize") })
+	if got != testConfigFile {
+		t.Errorf("got\n%s", got)
+	}
+}
+
+// a change that can't be made in the text keeps the comments and the
+// indentation
+func TestConfigFileEditRoundTrip(t *testing.T) {
+	got := editConfig(t, func(f *ConfigFile) error {
+		if err := f.Set("taskbar.contextmenu", []map[string]string{{"name": "Run", "shellExecute": "cmd.exe"}}); err != nil {
+			return err
+		}
+		return f.Set("taskbar.position", "top")
+	})
+	for _, want := range []string{
+		"# GoShell config\n",
+		"    position: top # or top\n",
+		"    contextmenu:\n        - name: Run\n          shellExecute: cmd.exe\n",
+		"# the desktop\ncontextmenu:\n    - name: Explorer\n      openProcess: explorer.exe\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%q is missing in\n%s", want, got)
+		}
+	}
+}
+
+func TestSetConfigValue(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte(testConfigFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetConfigValue(file, "taskbar.position", "top"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(testConfigFile, "position: bottom", "position: top", 1); string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	if err := SetConfigValue(filepath.Join(t.TempDir(), "missing.yaml"), "taskbar.position", "top"); err == nil {
+		t.Error("no error for a missing file")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(file, []byte("old content that is longer\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(file, []byte("new\n")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(file)
+	if err != nil || string(got) != "new\n" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	// no temporary file is left behind
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("got %d files in the folder", len(entries))
+	}
+
+	// a new file
+	if err := WriteFileAtomic(filepath.Join(dir, "new.yaml"), []byte("a\n")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration that is written like "30s" or "1m30s" in
+// config.yaml, a plain number are seconds.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var err error
+	if value.Kind != yaml.ScalarNode {
+		err = fmt.Errorf("expected a duration like \"30s\"")
+	} else if value.ShortTag() == "!!int" {
+		var seconds int
+		err = value.Decode(&seconds)
+		*d = Duration(time.Duration(seconds) * time.Second)
+	} else {
+		var v time.Duration
+		v, err = time.ParseDuration(value.Value)
+		*d = Duration(v)
+	}
+	if err == nil && *d < 0 {
+		err = fmt.Errorf("duration %q can't be negative", value.Value)
+	}
+	if err != nil {
+		// a TypeError lets the decoder continue with the other values
+		return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: %v", value.Line, err)}}
+	}
+	return nil
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
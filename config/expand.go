@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExpandVariables replaces the variables in s:
+//
+//	%VAR%               environment variable like Windows does it
+//	${VAR}              same as %VAR%
+//	${VAR:-fallback}    fallback if VAR is unset or empty
+//	{FOLDERID_Desktop}  known folder, https://learn.microsoft.com/en-us/windows/win32/shell/knownfolderid
+//	%% and $$           a literal % or $
+//
+// A string that is only a FOLDERID_x name is a known folder as well.
+// lookup resolves variable and FOLDERID_x names. Unknown variables are kept
+// as they are, so a typo stays visible in the result, and every unknown name
+// is reported in warnings.
+func ExpandVariables(s string, lookup func(name string) (string, bool)) (result string, warnings []string) {
+	if strings.HasPrefix(s, "FOLDERID_") && isVariableName(s) {
+		s = "{" + s + "}"
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '%' && i+1 < len(s) && s[i+1] == '%':
+			b.WriteByte('%')
+			i++
+
+		case c == '%':
+			end := strings.IndexByte(s[i+1:], '%')
+			if end == -1 || !isVariableName(s[i+1:i+1+end]) {
+				b.WriteByte(c) // just a percent sign like in "100%"
+				continue
+			}
+			name := s[i+1 : i+1+end]
+			if val, ok := lookup(name); ok {
+				b.WriteString(val)
+			} else {
+				b.WriteString(s[i : i+end+2])
+				warnings = append(warnings, fmt.Sprintf("unknown variable %%%s%%", name))
+			}
+			i += end + 1
+
+		case c == '$' && i+1 < len(s) && s[i+1] == '$':
+			b.WriteByte('$')
+			i++
+
+		case c == '$' && i+1 < len(s) && s[i+1] == '{':
+			end := closingBrace(s, i+2)
+			if end == -1 {
+				b.WriteByte(c)
+				continue
+			}
+			name, fallback, hasFallback := strings.Cut(s[i+2:end], ":-")
+			val, ok := lookup(name)
+			switch {
+			case ok && val != "":
+				b.WriteString(val)
+			case hasFallback:
+				val, w := ExpandVariables(fallback, lookup)
+				b.WriteString(val)
+				warnings = append(warnings, w...)
+			case !ok:
+				b.WriteString(s[i : end+1])
+				warnings = append(warnings, fmt.Sprintf("unknown variable ${%s}", name))
+			}
+			i = end
+
+		case c == '{' && strings.HasPrefix(s[i+1:], "FOLDERID_"):
+			end := strings.IndexByte(s[i+1:], '}')
+			if end == -1 {
+				b.WriteByte(c)
+				continue
+			}
+			name := s[i+1 : i+1+end]
+			if val, ok := lookup(name); ok {
+				b.WriteString(val)
+			} else {
+				b.WriteString(s[i : i+end+2])
+				warnings = append(warnings, fmt.Sprintf("unknown known folder {%s}", name))
+			}
+			i += end + 1
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), warnings
+}
+
+// names like ProgramFiles(x86) are valid on Windows
+func isVariableName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '_', r == '(', r == ')', r == '-', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// closingBrace returns the index of the } that closes ${ at start,
+// braces in a fallback are allowed
+func closingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
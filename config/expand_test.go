@@ -0,0 +1,122 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandVariables(t *testing.T) {
+	vars := map[string]string{
+		"WINDIR":                 `C:\Windows`,
+		"EMPTY":                  "",
+		"tools":                  `D:\Tools`,
+		"ProgramFiles(x86)":      `C:\Program Files (x86)`,
+		"FOLDERID_Desktop":       `C:\Users\a\Desktop`,
+		"FOLDERID_PublicDesktop": `C:\Users\Public\Desktop`,
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+
+	tests := []struct {
+		in       string
+		want     string
+		warnings []string
+	}{
+		{"", "", nil},
+		{"plain text", "plain text", nil},
+		{`%WINDIR%\explorer.exe`, `C:\Windows\explorer.exe`, nil},
+		{`${WINDIR}\explorer.exe`, `C:\Windows\explorer.exe`, nil},
+		{`%ProgramFiles(x86)%\app`, `C:\Program Files (x86)\app`, nil},
+		{"100%", "100%", nil},
+		{"50% or 60%", "50% or 60%", nil},
+		{"%%WINDIR%%", "%WINDIR%", nil},
+		{"$${WINDIR}", "${WINDIR}", nil},
+		{"cost: 5$", "cost: 5$", nil},
+		{"${EMPTY}", "", nil},
+		{"${EMPTY:-fb}", "fb", nil},
+		{"${MISSING:-fb}", "fb", nil},
+		{"${WINDIR:-fb}", `C:\Windows`, nil},
+		{"${MISSING:-}", "", nil},
+		{`${MISSING:-${tools}\bin}`, `D:\Tools\bin`, nil},
+		{`${MISSING:-${ALSO_MISSING:-%WINDIR%}}`, `C:\Windows`, nil},
+		{`${MISSING:-{FOLDERID_Desktop}}`, `C:\Users\a\Desktop`, nil},
+		{`{FOLDERID_Desktop}\sub`, `C:\Users\a\Desktop\sub`, nil},
+		{"FOLDERID_PublicDesktop", `C:\Users\Public\Desktop`, nil},
+		{"FOLDERID_Desktop and more", "FOLDERID_Desktop and more", nil},
+		{"{not a folder}", "{not a folder}", nil},
+		{"${unclosed", "${unclosed", nil},
+
+		// unknown names stay as they are
+		{`%MISSING%\x`, `%MISSING%\x`, []string{"unknown variable %MISSING%"}},
+		{`${MISSING}\x`, `${MISSING}\x`, []string{"unknown variable ${MISSING}"}},
+		{`{FOLDERID_Nothing}\x`, `{FOLDERID_Nothing}\x`, []string{"unknown known folder {FOLDERID_Nothing}"}},
+		{"FOLDERID_Nothing", "{FOLDERID_Nothing}", []string{"unknown known folder {FOLDERID_Nothing}"}},
+		{`${A:-${B}}`, `${B}`, []string{"unknown variable ${B}"}},
+	}
+	for _, tt := range tests {
+		got, warnings := ExpandVariables(tt.in, lookup)
+		if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+		if !reflect.DeepEqual(warnings, tt.warnings) {
+			t.Errorf("%q: got warnings %q, want %q", tt.in, warnings, tt.warnings)
+		}
+	}
+}
+
+func TestConfigLookupVariable(t *testing.T) {
+	t.Setenv("GOSHELL_TEST_HOME", `C:\Users\a`)
+	c := &Config{Variables: map[string]string{
+		"tools":  `${GOSHELL_TEST_HOME}\Tools`,
+		"editor": `%TOOLS%\notepad++.exe`,
+		"loop":   "${loop}x",
+	}}
+	for _, tt := range []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"Editor", `C:\Users\a\Tools\notepad++.exe`, true},
+		{"GOSHELL_TEST_HOME", `C:\Users\a`, true},
+		{"GOSHELL_TEST_MISSING", "", false},
+	} {
+		if got, ok := c.lookupVariable(tt.name); got != tt.want || ok != tt.ok {
+			t.Errorf("%s: got %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	// a variable that refers to itself ends after a few rounds
+	if got, ok := c.lookupVariable("loop"); !ok || got == "" {
+		t.Errorf("loop: got %q, %v", got, ok)
+	}
+}
+
+// the args are expanded like the paths, a literal % or $ for cmd.exe or
+// PowerShell is written doubled, the migration to version 4 did that
+func TestExpandVariablesArgs(t *testing.T) {
+	t.Setenv("GOSHELL_TEST_HOME", `C:\Users\a`)
+	c := &Config{
+		Variables: map[string]string{"tools": `D:\Tools`},
+		Contextmenu: []Contextmenu{{Name: "List", Action: Action{
+			OpenProcess: "cmd.exe",
+			Args:        []string{"/c", "for %%%%i in (*) do echo %%%%i", `${tools}\list.txt`, "%GOSHELL_TEST_HOME%", "100%"},
+		}}},
+		Hotkey: []Hotkey{{Buttons: "WIN+P", Action: Action{
+			OpenProcess: "powershell.exe",
+			Args:        []string{"-c", "echo $$$$", "echo $$Home"},
+		}}},
+	}
+	expandVariables(c)
+	for _, tt := range []struct {
+		got, want []string
+	}{
+		{c.Contextmenu[0].Args, []string{"/c", "for %%i in (*) do echo %%i", `D:\Tools\list.txt`, `C:\Users\a`, "100%"}},
+		{c.Hotkey[0].Args, []string{"-c", "echo $$", "echo $Home"}},
+	} {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// FileHandler says how a file of a folder menu, a file dropped on the desktop
+// or a file of the startup folders is opened. The action can use {path},
+// {dir} and {name} of the file.
+type FileHandler struct {
+	// an extension like ".ps1" or a pattern for the file name like "*.tar.gz"
+	Match     string `yaml:"match"`
+	Action    `yaml:",inline"`
+	MergeRule `yaml:",inline"`
+}
+
+// defaultFileHandlers are used after the ones of the config
+var defaultFileHandlers = []FileHandler{
+	// https://docs.microsoft.com/de-de/powershell/module/microsoft.powershell.core/about/about_powershell_exe
+	{Match: ".ps1", Action: Action{OpenProcess: "PowerShell.exe", Args: []string{"-NoLogo", "-NoProfile", "-ExecutionPolicy", "Bypass", "-File", "{path}"}, Workdir: "{dir}"}},
+	{Match: ".bat", Action: Action{OpenProcess: "cmd.exe", Args: []string{"/c", "{path}"}, Workdir: "{dir}"}},
+	{Match: ".cmd", Action: Action{OpenProcess: "cmd.exe", Args: []string{"/c", "{path}"}, Workdir: "{dir}"}},
+	// https://docs.python.org/3/using/windows.html#python-launcher-for-windows
+	{Match: ".py", Action: Action{OpenProcess: "py.exe", Args: []string{"{path}"}, Workdir: "{dir}"}},
+	{Match: ".url", Action: Action{ShellExecute: "{path}"}},
+	// ClickOnce applications
+	{Match: ".appref-ms", Action: Action{OpenProcess: "rundll32.exe", Args: []string{"dfshim.dll,ShOpenVerbShortcut", "{path}"}}},
+	// https://stackoverflow.com/a/12076082
+	{Match: "*", Action: Action{OpenProcess: "rundll32.exe", Args: []string{"url.dll,FileProtocolHandler", "{path}"}}},
+}
+
+// matches compares an extension with the end of the file name and a pattern
+// with the whole name, both case insensitive
+func (h *FileHandler) matches(name string) bool {
+	match, name := strings.ToLower(h.Match), strings.ToLower(name)
+	if strings.HasPrefix(match, ".") && !strings.ContainsAny(match, `*?[`) {
+		return strings.HasSuffix(name, match)
+	}
+	ok, _ := filepath.Match(match, name)
+	return ok
+}
+
+// FileAction returns the action that opens file, the handlers of the config
+// come first
+func FileAction(handlers []FileHandler, file string) Action {
+	name := filepath.Base(file)
+	for _, list := range [][]FileHandler{handlers, defaultFileHandlers} {
+		for i := range list {
+			if list[i].matches(name) {
+				return list[i].Action.forFile(file)
+			}
+		}
+	}
+	return Action{ShellExecute: file} // the defaults end with "*"
+}
+
+// forFile returns a copy of the action with the placeholders replaced
+func (a Action) forFile(file string) Action {
+	r := strings.NewReplacer(
+		"{path}", file,
+		"{dir}", filepath.Dir(file),
+		"{name}", filepath.Base(file),
+	)
+	a.expand(func(s *string) { *s = r.Replace(*s) }, func(list []string) []string {
+		result := make([]string, len(list))
+		for i, s := range list {
+			result[i] = r.Replace(s)
+		}
+		return result
+	})
+	return a
+}
@@ -0,0 +1,29 @@
+package config
+
+// FolderOptions are the options of a contextmenu entry with path
+type FolderOptions struct {
+	// name (default), natural (2 before 10), modified (newest first) or none
+	// (the order of path and then of the folder)
+	Sort         string `yaml:"sort,omitempty"`
+	FoldersFirst *bool  `yaml:"foldersFirst,omitempty"` // default true
+	// shows "Notepad" instead of "Notepad.lnk", default true
+	HideExtensions *bool `yaml:"hideExtensions,omitempty"`
+	// patterns for the file names, without a match a file is hidden
+	Include []string `yaml:"include,omitempty"`
+	// patterns for the names of files and folders that are hidden
+	Exclude []string `yaml:"exclude,omitempty"`
+	// levels of submenus, 1 shows only the files of path, 0 is unlimited
+	MaxDepth int `yaml:"maxDepth,omitempty"`
+	// a folder with a single entry is replaced by that entry
+	Flatten bool `yaml:"flatten,omitempty"`
+}
+
+// FoldersFirstOrDefault is FoldersFirst, true if it is not set
+func (o *FolderOptions) FoldersFirstOrDefault() bool {
+	return o.FoldersFirst == nil || *o.FoldersFirst
+}
+
+// HideExtensionsOrDefault is HideExtensions, true if it is not set
+func (o *FolderOptions) HideExtensionsOrDefault() bool {
+	return o.HideExtensions == nil || *o.HideExtensions
+}
@@ -0,0 +1,106 @@
+//go:build ignore
+
+// genkeys writes virtualkeys.go with the key names of winc.String2key, so
+// the hotkeys can be checked without Windows. It reads the source of winc,
+// the package itself only builds for Windows.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"strconv"
+)
+
+func main() {
+	fset := token.NewFileSet()
+	parse := func(file string) *ast.File {
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return f
+	}
+	keyboard := parse("../winc/keyboard.go")
+	w32 := constants(parse("../winc/w32/constants.go"))
+	keys := constants(keyboard)
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by genkeys.go from winc/keyboard.go; DO NOT EDIT.\n\n")
+	b.WriteString("package config\n\n")
+	b.WriteString("// virtualKeys are the key names of winc.String2key with their virtual key\n")
+	b.WriteString("// codes, here to check the hotkeys without Windows\n")
+	b.WriteString("// https://learn.microsoft.com/en-us/windows/win32/inputdev/virtual-key-codes\n")
+	b.WriteString("var virtualKeys = map[string]uint32{\n")
+	for _, kv := range string2key(keyboard) {
+		name, _ := strconv.Unquote(kv.Key.(*ast.BasicLit).Value)
+		code, err := value(keys[kv.Value.(*ast.Ident).Name], w32)
+		if err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+		fmt.Fprintf(&b, "%q: 0x%02X,\n", name, code)
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("virtualkeys.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// constants returns the expressions of the constants of f by name
+func constants(f *ast.File) map[string]ast.Expr {
+	consts := map[string]ast.Expr{}
+	for _, decl := range f.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.CONST {
+			for _, spec := range d.Specs {
+				s := spec.(*ast.ValueSpec)
+				for i, name := range s.Names {
+					if i < len(s.Values) {
+						consts[name.Name] = s.Values[i]
+					}
+				}
+			}
+		}
+	}
+	return consts
+}
+
+// string2key returns the entries of the String2key map in their order
+func string2key(f *ast.File) (entries []*ast.KeyValueExpr) {
+	ast.Inspect(f, func(n ast.Node) bool {
+		s, ok := n.(*ast.ValueSpec)
+		if !ok || len(s.Names) != 1 || s.Names[0].Name != "String2key" {
+			return true
+		}
+		for _, elt := range s.Values[0].(*ast.CompositeLit).Elts {
+			entries = append(entries, elt.(*ast.KeyValueExpr))
+		}
+		return false
+	})
+	if entries == nil {
+		log.Fatal("String2key not found")
+	}
+	return
+}
+
+// value returns a number or a w32 constant like w32.VK_MENU
+func value(e ast.Expr, w32 map[string]ast.Expr) (uint64, error) {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		return strconv.ParseUint(e.Value, 0, 16)
+	case *ast.SelectorExpr:
+		if v, ok := w32[e.Sel.Name]; ok {
+			return value(v, w32)
+		}
+	}
+	return 0, fmt.Errorf("can't evaluate %T", e)
+}
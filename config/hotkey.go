@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// the modifiers of RegisterHotKey
+// https://learn.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-registerhotkey
+const (
+	ModAlt     = 0x0001
+	ModControl = 0x0002
+	ModShift   = 0x0004
+	ModWin     = 0x0008
+)
+
+// ParseHotkey splits a combination like "WIN+SHIFT+S" into the modifiers and
+// the virtual key code for RegisterHotKey.
+func ParseHotkey(buttons string) (fsModifiers, vk uint32, err error) {
+	var key string
+	for _, v := range strings.Split(buttons, "+") {
+		v = strings.TrimSpace(v)
+		switch strings.ToUpper(v) {
+		case "WIN":
+			fsModifiers |= ModWin
+
+		case "ALT":
+			fsModifiers |= ModAlt
+
+		case "CTRL", "STRG":
+			fsModifiers |= ModControl
+
+		case "SHIFT":
+			fsModifiers |= ModShift
+
+		case "":
+			return 0, 0, fmt.Errorf("hotkey %q: empty key", buttons)
+
+		default:
+			if key != "" {
+				return 0, 0, fmt.Errorf("hotkey %q: only one key besides the modifiers is allowed, got %q and %q", buttons, key, v)
+			}
+			key = v
+		}
+	}
+
+	if key == "" {
+		return 0, 0, fmt.Errorf("hotkey %q: missing key", buttons)
+	}
+	if vk, ok := virtualKey(key); ok {
+		return fsModifiers, vk, nil
+	}
+	return 0, 0, fmt.Errorf("hotkey %q: unknown key %q", buttons, key)
+}
+
+//go:generate go run genkeys.go
+
+// virtualKey returns the virtual key code of a key name of winc.String2key,
+// the case is ignored
+func virtualKey(name string) (uint32, bool) {
+	for n, vk := range virtualKeys {
+		if strings.EqualFold(n, name) {
+			return vk, true
+		}
+	}
+	return 0, false
+}
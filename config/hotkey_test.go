@@ -0,0 +1,55 @@
+package config
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+)
+
+// winc only builds for Windows, its source is read to check that every key
+// name of winc.String2key resolves here too
+func TestVirtualKeysResolve(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "../winc/keyboard.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		if s, ok := n.(*ast.ValueSpec); ok && s.Names[0].Name == "String2key" {
+			for _, elt := range s.Values[0].(*ast.CompositeLit).Elts {
+				name, _ := strconv.Unquote(elt.(*ast.KeyValueExpr).Key.(*ast.BasicLit).Value)
+				names = append(names, name)
+			}
+		}
+		return true
+	})
+	if len(names) == 0 || len(names) != len(virtualKeys) {
+		t.Errorf("got %d keys, winc has %d, run go generate", len(virtualKeys), len(names))
+	}
+	for _, name := range names {
+		if _, ok := virtualKey(name); !ok {
+			t.Errorf("%q doesn't resolve, run go generate", name)
+		}
+	}
+}
+
+func TestParseHotkeyKeys(t *testing.T) {
+	for _, tt := range []struct {
+		buttons   string
+		modifiers uint32
+		vk        uint32
+	}{
+		{"WIN+SHIFT+S", ModWin | ModShift, 0x53},
+		{"ctrl+alt+delete", ModControl | ModAlt, 0x2E},
+		{"STRG+F12", ModControl, 0x7B},
+		{"WIN + lwin", ModWin, 0x5B},
+		{"Kana / Hangul", 0, 0x15},
+	} {
+		modifiers, vk, err := ParseHotkey(tt.buttons)
+		if err != nil || modifiers != tt.modifiers || vk != tt.vk {
+			t.Errorf("%q: got 0x%X, 0x%02X, %v, want 0x%X, 0x%02X", tt.buttons, modifiers, vk, err, tt.modifiers, tt.vk)
+		}
+	}
+}
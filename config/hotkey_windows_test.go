@@ -0,0 +1,19 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/leaanthony/winc"
+)
+
+// virtualkeys.go is generated from winc, go generate updates it
+func TestVirtualKeysOfWinc(t *testing.T) {
+	for name, k := range winc.String2key {
+		if vk, ok := virtualKey(name); !ok || vk != uint32(k) {
+			t.Errorf("%q: got 0x%02X, %v, want 0x%02X", name, vk, ok, k)
+		}
+	}
+	if len(virtualKeys) != len(winc.String2key) {
+		t.Errorf("got %d keys, winc has %d", len(virtualKeys), len(winc.String2key))
+	}
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// MatchName compares a file or value name with a name or pattern of the
+// config, case insensitive
+func MatchName(pattern, name string) bool {
+	ok, _ := filepath.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return ok
+}
+
+// MatchNames reports if one of the patterns matches name
+func MatchNames(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if MatchName(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,360 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MergeRule defines how an entry of the contextmenu or hotkey list of an
+// include or overlay is merged into the list it is applied to.
+type MergeRule struct {
+	// append (default), replace or insertBefore
+	Merge string `yaml:"merge,omitempty"`
+	// name (buttons for hotkeys) of the entry to replace or to insert before,
+	// replace uses the name of the entry itself if it's empty
+	Target string `yaml:"target,omitempty"`
+
+	// where the rule is written, for the error if the target is missing
+	file         string
+	line, column int
+}
+
+// MergeConfig returns a new config with overlay applied on top of base.
+// Values that are written in overlay replace the ones of base, also with
+// false, 0 or an empty list. The contextmenu and hotkey lists are merged
+// entry by entry according to their MergeRule. Neither base nor overlay are
+// modified. A replace or insertBefore without its target is appended and
+// reported with the position of the overlay entry.
+func MergeConfig(base, overlay *Config) (*Config, ConfigErrors) {
+	c := *base
+	var errs ConfigErrors
+	mergeValue(reflect.ValueOf(&c).Elem(), reflect.ValueOf(overlay).Elem(), "", overlay.set, &errs)
+	c.files = nil
+	if overlay.set != nil {
+		c.set = make(map[string]bool, len(base.set)+len(overlay.set))
+		for _, set := range []map[string]bool{base.set, overlay.set} {
+			for path := range set {
+				c.set[path] = true
+			}
+		}
+	}
+	return &c, errs
+}
+
+// keyPaths adds the paths of the keys of the mapping n below path to set,
+// the entries of lists are not added
+func keyPaths(n *yaml.Node, path string, set map[string]bool) map[string]bool {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind != yaml.MappingNode {
+		return set
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key := n.Content[i].Value
+		if path != "" {
+			key = path + "." + key
+		}
+		set[key] = true
+		keyPaths(n.Content[i+1], key, set)
+	}
+	return set
+}
+
+// subPaths returns the paths below prefix without it, nil for nil
+func subPaths(set map[string]bool, prefix string) map[string]bool {
+	if set == nil {
+		return nil
+	}
+	sub := map[string]bool{}
+	for path := range set {
+		if rest, ok := strings.CutPrefix(path, prefix+"."); ok {
+			sub[rest] = true
+		}
+	}
+	return sub
+}
+
+// ApplyOverlays merges the overlays for the computer and then for the user.
+// The names are compared case insensitive like Windows does.
+func ApplyOverlays(c *Config, hostname, username string) (*Config, ConfigErrors) {
+	overlays := []struct {
+		name, key string
+		overlays  map[string]Config
+	}{
+		{hostname, "host", c.Overlay.Host},
+		{username, "user", c.Overlay.User},
+	}
+
+	result := *c
+	var errs ConfigErrors
+	for _, o := range overlays {
+		for name, overlay := range o.overlays {
+			if o.name != "" && strings.EqualFold(name, o.name) {
+				overlay := overlay
+				overlay.set = subPaths(c.set, "overlay."+o.key+"."+name)
+				overlay.Include = nil
+				overlay.Overlay.Host, overlay.Overlay.User = nil, nil
+				merged, mergeErrs := MergeConfig(&result, &overlay)
+				result = *merged
+				errs = append(errs, mergeErrs...)
+			}
+		}
+	}
+	result.Overlay.Host, result.Overlay.User = nil, nil
+	return &result, errs
+}
+
+// ApplyLocks returns c with the keys of machine.Lock set back to the values
+// of machine, so a user config can't override them.
+func ApplyLocks(c, machine *Config) *Config {
+	result := *c
+	dst := reflect.ValueOf(&result).Elem()
+	src := reflect.ValueOf(machine).Elem()
+	for _, path := range machine.Lock {
+		d, ok := fieldByPath(dst, path)
+		if !ok {
+			continue // reported by ValidateConfig
+		}
+		s, _ := fieldByPath(src, path)
+		d.Set(s)
+	}
+	result.Lock = machine.Lock
+	return &result
+}
+
+// fieldByPath returns the field for a path of yaml keys like
+// "taskbar.button.size", the keys are compared case insensitive
+func fieldByPath(v reflect.Value, path string) (reflect.Value, bool) {
+	for _, key := range strings.Split(path, ".") {
+		if v.Kind() != reflect.Struct || reflect.PointerTo(v.Type()).Implements(unmarshalerType) {
+			return reflect.Value{}, false
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() || f.Anonymous {
+				continue
+			}
+			if name, _, _ := strings.Cut(f.Tag.Get("yaml"), ","); strings.EqualFold(name, key) {
+				v, found = v.Field(i), true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
+
+var (
+	contextmenuListType = reflect.TypeOf([]Contextmenu{})
+	hotkeyListType      = reflect.TypeOf([]Hotkey{})
+	serviceListType     = reflect.TypeOf([]Service{})
+	fileHandlerListType = reflect.TypeOf([]FileHandler{})
+	startupRuleListType = reflect.TypeOf([]StartupRule{})
+)
+
+// mergeValue merges src into dst, path is the key of src in the overlay. A
+// value is set when its path is in set, without set when it isn't zero.
+func mergeValue(dst, src reflect.Value, path string, set map[string]bool, errs *ConfigErrors) {
+	switch {
+	case dst.Type() == contextmenuListType:
+		dst.Set(reflect.ValueOf(mergeList(dst.Interface().([]Contextmenu), src.Interface().([]Contextmenu),
+			func(m *Contextmenu) string { return m.Name }, strings.ToLower,
+			func(m *Contextmenu) *MergeRule { return &m.MergeRule }, errs,
+		)))
+
+	case dst.Type() == hotkeyListType:
+		dst.Set(reflect.ValueOf(mergeList(dst.Interface().([]Hotkey), src.Interface().([]Hotkey),
+			func(h *Hotkey) string { return h.Buttons }, hotkeyKey,
+			func(h *Hotkey) *MergeRule { return &h.MergeRule }, errs,
+		)))
+
+	case dst.Type() == serviceListType:
+		dst.Set(reflect.ValueOf(mergeList(dst.Interface().([]Service), src.Interface().([]Service),
+			func(s *Service) string { return s.Name }, strings.ToLower,
+			func(s *Service) *MergeRule { return &s.MergeRule }, errs,
+		)))
+
+	case dst.Type() == fileHandlerListType:
+		dst.Set(reflect.ValueOf(mergeList(dst.Interface().([]FileHandler), src.Interface().([]FileHandler),
+			func(h *FileHandler) string { return h.Match }, strings.ToLower,
+			func(h *FileHandler) *MergeRule { return &h.MergeRule }, errs,
+		)))
+
+	case dst.Type() == startupRuleListType:
+		dst.Set(reflect.ValueOf(mergeList(dst.Interface().([]StartupRule), src.Interface().([]StartupRule),
+			func(r *StartupRule) string { return r.Name }, strings.ToLower,
+			func(r *StartupRule) *MergeRule { return &r.MergeRule }, errs,
+		)))
+
+	// a Color is one value and not merged channel by channel
+	case dst.Kind() == reflect.Struct && !reflect.PointerTo(dst.Type()).Implements(unmarshalerType):
+		for i := 0; i < dst.NumField(); i++ {
+			f := dst.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			fieldPath := path
+			if key, inline := yamlKey(f); !inline && path != "" {
+				fieldPath = path + "." + key
+			} else if !inline {
+				fieldPath = key
+			}
+			mergeValue(dst.Field(i), src.Field(i), fieldPath, set, errs)
+		}
+
+	case dst.Kind() == reflect.Map:
+		if src.Len() == 0 {
+			return
+		}
+		// copy, the map of base must not change
+		m := reflect.MakeMap(dst.Type())
+		iter := dst.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(iter.Key(), iter.Value())
+		}
+		iter = src.MapRange()
+		for iter.Next() {
+			v := reflect.New(dst.Type().Elem()).Elem()
+			if old := m.MapIndex(iter.Key()); old.IsValid() {
+				v.Set(old)
+				mergeValue(v, iter.Value(), path+"."+iter.Key().String(), set, errs)
+			} else {
+				v.Set(iter.Value())
+			}
+			m.SetMapIndex(iter.Key(), v)
+		}
+		dst.Set(m)
+
+	default:
+		// without the keys of the file zero means not set
+		if set != nil && set[path] || set == nil && !src.IsZero() {
+			dst.Set(src)
+		}
+	}
+}
+
+// hotkeyKey makes "Win + E" and "WIN+E" the same hotkey
+func hotkeyKey(buttons string) string {
+	return strings.ToUpper(strings.ReplaceAll(buttons, " ", ""))
+}
+
+// mergeList merges the entries of overlay into base by their MergeRule. The
+// names of the entries and the targets are compared after normalize.
+func mergeList[T any](base, overlay []T, name func(*T) string, normalize func(string) string, rule func(*T) *MergeRule, errs *ConfigErrors) []T {
+	if len(overlay) == 0 {
+		return base
+	}
+
+	list := make([]T, len(base), len(base)+len(overlay))
+	copy(list, base)
+	for _, item := range overlay {
+		r := *rule(&item)
+		*rule(&item) = MergeRule{}
+
+		mode := strings.ToLower(r.Merge)
+		target := r.Target
+		if target == "" && mode == "replace" {
+			target = name(&item)
+		}
+		i := -1
+		for j := range list {
+			if target != "" && normalize(name(&list[j])) == normalize(target) {
+				i = j
+				break
+			}
+		}
+
+		switch {
+		case mode == "replace" && i != -1:
+			list[i] = item
+		case mode == "insertbefore" && i != -1:
+			list = append(list[:i+1], list[i:]...)
+			list[i] = item
+		default:
+			if mode == "replace" || mode == "insertbefore" {
+				*errs = append(*errs, ConfigError{File: r.file, Line: r.line, Column: r.column,
+					Msg: fmt.Sprintf("%s: target %q not found, the entry is appended", r.Merge, target)})
+			}
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+var mergeRuleType = reflect.TypeOf(MergeRule{})
+
+// setMergePositions remembers in the MergeRules of c where their entries are
+// written in doc, the node c was decoded from
+func setMergePositions(v reflect.Value, n *yaml.Node, file string) {
+	if n == nil {
+		return
+	}
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			setMergePositions(v.Elem(), n, file)
+		}
+
+	case reflect.Struct:
+		if v.Type() == mergeRuleType {
+			pos := n
+			if t := lookupNode(n, "target"); t != nil {
+				pos = t
+			} else if m := lookupNode(n, "merge"); m != nil {
+				pos = m
+			}
+			r := v.Addr().Interface().(*MergeRule)
+			r.file, r.line, r.column = file, pos.Line, pos.Column
+			return
+		}
+		if n.Kind != yaml.MappingNode || reflect.PointerTo(v.Type()).Implements(unmarshalerType) {
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if key, inline := yamlKey(f); inline {
+				setMergePositions(v.Field(i), n, file)
+			} else if key != "-" {
+				setMergePositions(v.Field(i), lookupNode(n, key), file)
+			}
+		}
+
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode || len(n.Content) != v.Len() {
+			return
+		}
+		for i := range n.Content {
+			setMergePositions(v.Index(i), n.Content[i], file)
+		}
+
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode || v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		// the values of a map can't be changed in place
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := reflect.ValueOf(n.Content[i].Value).Convert(v.Type().Key())
+			old := v.MapIndex(key)
+			if !old.IsValid() {
+				continue
+			}
+			val := reflect.New(old.Type()).Elem()
+			val.Set(old)
+			setMergePositions(val, n.Content[i+1], file)
+			v.SetMapIndex(key, val)
+		}
+	}
+}
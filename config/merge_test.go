@@ -0,0 +1,315 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decodeConfig decodes a config like the loader does, with the positions of
+// the merge rules
+func decodeConfig(t *testing.T, file, content string) *Config {
+	t.Helper()
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		t.Fatal(err)
+	}
+	c := new(Config)
+	if err := root.Decode(c); err != nil {
+		t.Fatal(err)
+	}
+	setMergePositions(reflect.ValueOf(c).Elem(), root.Content[0], file)
+	c.set = keyPaths(root.Content[0], "", map[string]bool{})
+	return c
+}
+
+func menuNames(list []Contextmenu) (names []string) {
+	for _, m := range list {
+		names = append(names, m.Name)
+	}
+	return
+}
+
+func TestMergeConfigLists(t *testing.T) {
+	base := decodeConfig(t, "base.yaml", `
+contextmenu:
+- name: Explorer
+  openProcess: explorer.exe
+- name: Terminal
+  openProcess: wt.exe
+hotkey:
+- buttons: WIN+E
+  openProcess: explorer.exe
+`)
+	overlay := decodeConfig(t, "overlay.yaml", `
+contextmenu:
+- name: terminal
+  openProcess: cmd.exe
+  merge: replace
+- name: Editor
+  openProcess: notepad.exe
+  merge: insertBefore
+  target: TERMINAL
+- name: Calculator
+  openProcess: calc.exe
+hotkey:
+- buttons: Win + E
+  openProcess: totalcmd.exe
+  merge: replace
+`)
+	c, errs := MergeConfig(base, overlay)
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	if got, want := menuNames(c.Contextmenu), []string{"Explorer", "Editor", "terminal", "Calculator"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("contextmenu: got %q, want %q", got, want)
+	}
+	if got := c.Contextmenu[2].OpenProcess; got != "cmd.exe" {
+		t.Errorf("replace: got %q", got)
+	}
+	if len(c.Hotkey) != 1 || c.Hotkey[0].OpenProcess != "totalcmd.exe" {
+		t.Errorf("hotkey \"Win + E\" didn't replace WIN+E: %+v", c.Hotkey)
+	}
+	if c.Contextmenu[1].MergeRule != (MergeRule{}) {
+		t.Errorf("the merge rule is kept in the result: %+v", c.Contextmenu[1].MergeRule)
+	}
+
+	// base is not modified
+	if got, want := menuNames(base.Contextmenu), []string{"Explorer", "Terminal"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("base was changed to %q", got)
+	}
+}
+
+func TestMergeConfigTargetNotFound(t *testing.T) {
+	base := decodeConfig(t, "base.yaml", `
+contextmenu:
+- name: Explorer
+  openProcess: explorer.exe
+`)
+	overlay := decodeConfig(t, "overlay.yaml", `
+contextmenu:
+- name: Terminal
+  openProcess: wt.exe
+  merge: replace
+- name: Editor
+  openProcess: notepad.exe
+  merge: insertBefore
+  target: Calculator
+services:
+- name: sync
+  program: sync.exe
+  merge: replace
+  target: backup
+`)
+	c, errs := MergeConfig(base, overlay)
+	want := ConfigErrors{
+		{File: "overlay.yaml", Line: 5, Column: 10, Msg: `replace: target "Terminal" not found, the entry is appended`},
+		{File: "overlay.yaml", Line: 9, Column: 11, Msg: `insertBefore: target "Calculator" not found, the entry is appended`},
+		{File: "overlay.yaml", Line: 14, Column: 11, Msg: `replace: target "backup" not found, the entry is appended`},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("got\n%v\nwant\n%v", errs, want)
+	}
+	if got, want := menuNames(c.Contextmenu), []string{"Explorer", "Terminal", "Editor"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("contextmenu: got %q, want %q", got, want)
+	}
+}
+
+func TestMergeConfigValues(t *testing.T) {
+	base := decodeConfig(t, "base.yaml", `
+taskbar:
+  height: 30
+  position: top
+variables:
+  a: "1"
+  b: "2"
+`)
+	overlay := decodeConfig(t, "overlay.yaml", `
+taskbar:
+  height: 40
+variables:
+  b: "3"
+`)
+	c, errs := MergeConfig(base, overlay)
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	if c.Taskbar.Height != 40 || c.Taskbar.Position != "top" {
+		t.Errorf("taskbar: got %+v", c.Taskbar)
+	}
+	if want := map[string]string{"a": "1", "b": "3"}; !reflect.DeepEqual(c.Variables, want) {
+		t.Errorf("variables: got %v, want %v", c.Variables, want)
+	}
+	if base.Variables["b"] != "2" {
+		t.Errorf("the variables of base were changed")
+	}
+}
+
+// a written false, 0 or empty list overrides the base, a missing key doesn't
+func TestMergeConfigZeroValues(t *testing.T) {
+	base := decodeConfig(t, "base.yaml", `
+desktop:
+  contextmenu:
+    darkMode: true
+    addDebugEntry: true
+taskbar:
+  fontSize: 12
+  height: 30
+startup:
+  exclude: [OneDrive]
+variables:
+  a: "1"
+`)
+	overlay := decodeConfig(t, "overlay.yaml", `
+desktop:
+  contextmenu:
+    darkMode: false
+taskbar:
+  fontSize: 0
+startup:
+  exclude: []
+variables:
+  a: ""
+`)
+	c, errs := MergeConfig(base, overlay)
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	if c.Desktop.Contextmenu.DarkMode || !c.Desktop.Contextmenu.AddDebugEntry {
+		t.Errorf("desktop: got %+v", c.Desktop.Contextmenu)
+	}
+	if c.Taskbar.FontSize != 0 || c.Taskbar.Height != 30 {
+		t.Errorf("taskbar: got fontSize %d, height %d", c.Taskbar.FontSize, c.Taskbar.Height)
+	}
+	if len(c.Startup.Exclude) != 0 {
+		t.Errorf("startup.exclude: got %q", c.Startup.Exclude)
+	}
+	if v, ok := c.Variables["a"]; !ok || v != "" {
+		t.Errorf("variables: got %q", c.Variables)
+	}
+
+	// the merged config keeps what was written, for the next overlay
+	again, _ := MergeConfig(base, c)
+	if again.Desktop.Contextmenu.DarkMode {
+		t.Error("darkMode is true again after a second merge")
+	}
+}
+
+func TestApplyOverlaysFalse(t *testing.T) {
+	c := decodeConfig(t, "config.yaml", `
+desktop:
+  contextmenu:
+    darkMode: true
+overlay:
+  user:
+    Guest:
+      desktop:
+        contextmenu:
+          darkMode: false
+`)
+	if got, _ := ApplyOverlays(c, "laptop", "guest"); got.Desktop.Contextmenu.DarkMode {
+		t.Error("the user overlay didn't turn darkMode off")
+	}
+	if got, _ := ApplyOverlays(c, "laptop", "admin"); !got.Desktop.Contextmenu.DarkMode {
+		t.Error("darkMode is off without the overlay")
+	}
+}
+
+func TestApplyOverlays(t *testing.T) {
+	c := decodeConfig(t, "config.yaml", `
+taskbar:
+  height: 30
+overlay:
+  host:
+    LAPTOP:
+      taskbar:
+        height: 40
+      contextmenu:
+      - name: Battery
+        openProcess: powercfg.cpl
+        merge: insertBefore
+        target: Docking
+  user:
+    admin:
+      taskbar:
+        height: 50
+`)
+	got, errs := ApplyOverlays(c, "laptop", "guest")
+	if got.Taskbar.Height != 40 {
+		t.Errorf("height: got %d, want 40 of the host overlay", got.Taskbar.Height)
+	}
+	if got.Overlay.Host != nil || got.Overlay.User != nil {
+		t.Errorf("the overlays are kept in the result")
+	}
+	want := ConfigErrors{{File: "config.yaml", Line: 13, Column: 17, Msg: `insertBefore: target "Docking" not found, the entry is appended`}}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("got %v, want %v", errs, want)
+	}
+
+	if got, _ := ApplyOverlays(c, "desktop", "ADMIN"); got.Taskbar.Height != 50 {
+		t.Errorf("height: got %d, want 50 of the user overlay", got.Taskbar.Height)
+	}
+}
+
+func TestApplyLocks(t *testing.T) {
+	user := decodeConfig(t, "user.yaml", `
+taskbar:
+  height: 40
+  position: top
+`)
+	machine := decodeConfig(t, "machine.yaml", `
+taskbar:
+  height: 30
+lock:
+- Taskbar.Height
+`)
+	c := ApplyLocks(user, machine)
+	if c.Taskbar.Height != 30 || c.Taskbar.Position != "top" {
+		t.Errorf("got %+v", c.Taskbar)
+	}
+}
+
+// the errors of an include point into the file that has the merge rule
+func TestReadConfigMergeErrors(t *testing.T) {
+	file := writeConfig(t, "config.yaml", `version: 2
+include:
+- common.yaml
+contextmenu:
+- name: Terminal
+  openProcess: wt.exe
+  merge: replace
+`)
+	common := filepath.Join(filepath.Dir(file), "common.yaml")
+	if err := os.WriteFile(common, []byte("version: 2\ncontextmenu:\n- name: Explorer\n  openProcess: explorer.exe\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ReadConfig(file)
+	want := ConfigErrors{{File: file, Line: 7, Column: 10, Msg: `replace: target "Terminal" not found, the entry is appended`}}
+	if errs, _ := err.(ConfigErrors); !reflect.DeepEqual(errs, want) {
+		t.Errorf("got %v, want %v", err, want)
+	}
+}
+
+func TestReadConfigIncludeFalse(t *testing.T) {
+	file := writeConfig(t, "config.yaml", `version: 4
+include:
+- common.yaml
+desktop:
+  contextmenu:
+    darkMode: false
+`)
+	common := filepath.Join(filepath.Dir(file), "common.yaml")
+	if err := os.WriteFile(common, []byte("version: 4\ndesktop:\n  contextmenu:\n    darkMode: true\n    addDebugEntry: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Desktop.Contextmenu.DarkMode || !c.Desktop.Contextmenu.AddDebugEntry {
+		t.Errorf("got %+v", c.Desktop.Contextmenu)
+	}
+}
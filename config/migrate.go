@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configVersion is the version of the current Config, a file without
+// version is version 1
+const configVersion = 4
+
+// migrations[i] upgrades a document from version i+1 to i+2. A migration
+// changes doc (the file or one of its overlays) through f, so comments and
+// the formatting stay when the file is written back.
+var migrations = []func(f *ConfigFile, doc *yaml.Node) error{
+	migrateTaskbarAlpha,
+	migrateShutdownArgs,
+	migrateArgsEscapes,
+}
+
+// MigrateConfig upgrades a config file step by step to configVersion and
+// returns the version it had before.
+func MigrateConfig(f *ConfigFile) (from int, err error) {
+	doc := f.root.Content[0]
+	if doc.Kind != yaml.MappingNode || len(doc.Content) == 0 {
+		return configVersion, nil // nothing to migrate, ValidateConfig reports the rest
+	}
+
+	from = 1
+	if n := lookupNode(doc, "version"); n != nil {
+		from, err = strconv.Atoi(n.Value)
+		if err != nil || from < 1 {
+			return 0, fmt.Errorf("version %q is not a valid version", n.Value)
+		}
+	}
+	if from > configVersion {
+		return from, fmt.Errorf("version %d is newer than this GoShell supports (%d)", from, configVersion)
+	}
+
+	for v := from; v < configVersion; v++ {
+		for _, d := range configDocuments(doc) {
+			if err := migrations[v-1](f, d); err != nil {
+				return from, fmt.Errorf("migration from version %d to %d: %w", v, v+1, err)
+			}
+		}
+	}
+	if from < configVersion {
+		version := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(configVersion)}
+		f.setValue(doc, "version", version, true)
+	}
+	return from, nil
+}
+
+// MigrateConfigFile writes the migrated version of file and keeps the
+// original as file.bak
+func MigrateConfigFile(file string) error {
+	f, err := OpenConfigFile(file)
+	if err != nil {
+		return err
+	}
+	from, err := MigrateConfig(f)
+	if err != nil || from == configVersion {
+		return err
+	}
+
+	if err := WriteFileAtomic(file+".bak", f.content); err != nil {
+		return err
+	}
+	log.Printf("migrated %s from version %d to %d", file, from, configVersion)
+	return f.Save()
+}
+
+// configDocuments returns doc and the overlays in it, they have the same shape
+func configDocuments(doc *yaml.Node) []*yaml.Node {
+	docs := []*yaml.Node{doc}
+	for _, kind := range []string{"host", "user"} {
+		overlays := lookupNode(doc, "overlay", kind)
+		if overlays == nil || overlays.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 1; i < len(overlays.Content); i += 2 {
+			if overlays.Content[i].Kind == yaml.MappingNode {
+				docs = append(docs, overlays.Content[i])
+			}
+		}
+	}
+	return docs
+}
+
+// version 1 -> 2: "a" of taskbar.bgcolor was ignored, now it is the opacity
+// and the usual "a: 0" would make the taskbar invisible
+func migrateTaskbarAlpha(f *ConfigFile, doc *yaml.Node) error {
+	f.deleteKey(lookupNode(doc, "taskbar", "bgcolor"), "a")
+	return nil
+}
+
+// version 2 -> 3: the Reboot entry of the example config had the argument
+// "/t 0", which was passed on unquoted. Now every argument is quoted as one,
+// which shutdown.exe doesn't accept, so the arguments of shutdown are split.
+func migrateShutdownArgs(f *ConfigFile, doc *yaml.Node) error {
+	for _, args := range actionArgs(doc) {
+		if !isShutdown(args.item) {
+			continue
+		}
+		// from the end, the indexes of the others stay
+		for i := len(args.list.Content) - 1; i >= 0; i-- {
+			arg := args.list.Content[i]
+			fields := strings.Fields(arg.Value)
+			if arg.Kind != yaml.ScalarNode || len(fields) < 2 {
+				continue
+			}
+			items := make([]*yaml.Node, len(fields))
+			for j, field := range fields {
+				items[j] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: field, Style: arg.Style}
+			}
+			f.replaceItem(args.list, i, items)
+		}
+	}
+	return nil
+}
+
+// version 3 -> 4: the arguments were passed on as they are, now their
+// variables are expanded and %% and $$ are a literal % and $. They are
+// doubled, so e.g. the %%i of a batch file or PowerShell's $$ stay.
+func migrateArgsEscapes(f *ConfigFile, doc *yaml.Node) error {
+	escape := strings.NewReplacer("%%", "%%%%", "$$", "$$$$")
+	for _, args := range actionArgs(doc) {
+		for _, arg := range args.list.Content {
+			if arg.Kind != yaml.ScalarNode || escape.Replace(arg.Value) == arg.Value {
+				continue
+			}
+			f.setScalar(arg, escape.Replace(arg.Value))
+		}
+	}
+	return nil
+}
+
+// itemArgs is the args list of a menu entry or a hotkey
+type itemArgs struct {
+	item, list *yaml.Node
+}
+
+// actionArgs returns the args lists of the menu entries and the hotkeys
+func actionArgs(doc *yaml.Node) (args []itemArgs) {
+	for _, list := range []*yaml.Node{
+		lookupNode(doc, "contextmenu"),
+		lookupNode(doc, "taskbar", "contextmenu"),
+		lookupNode(doc, "hotkey"),
+	} {
+		if list == nil || list.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, item := range list.Content {
+			if l := lookupNode(item, "args"); l != nil && l.Kind == yaml.SequenceNode {
+				args = append(args, itemArgs{item, l})
+			}
+		}
+	}
+	return
+}
+
+// isShutdown reports if an entry starts shutdown.exe
+func isShutdown(item *yaml.Node) bool {
+	for _, kind := range actionKinds {
+		if n := lookupNode(item, kind.key); n != nil {
+			name := strings.ToLower(n.Value[strings.LastIndexAny(n.Value, `\/`)+1:])
+			return name == "shutdown" || name == "shutdown.exe"
+		}
+	}
+	return false
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// migrationTests are the documents before and after each migration, the
+// index is the version that is migrated from
+var migrationTests = map[int][]struct {
+	name          string
+	before, after string
+}{
+	1: {
+		{"alpha of the taskbar", `
+taskbar:
+  bgcolor:
+    r: 20
+    g: 20
+    b: 20
+    a: 0
+`, `
+taskbar:
+  bgcolor:
+    r: 20
+    g: 20
+    b: 20
+`},
+		{"alpha in an overlay", `
+overlay:
+  host:
+    laptop:
+      taskbar:
+        bgcolor: {r: 1, g: 2, b: 3, a: 0}
+`, `
+overlay:
+  host:
+    laptop:
+      taskbar:
+        bgcolor: {r: 1, g: 2, b: 3}
+`},
+		{"button colors stay", `
+taskbar:
+  button:
+    bgcolor: {r: 1, g: 2, b: 3, a: 0}
+`, `
+taskbar:
+  button:
+    bgcolor: {r: 1, g: 2, b: 3, a: 0}
+`},
+		{"without taskbar", "contextmenu: []\n", "contextmenu: []\n"},
+	},
+	2: {
+		{"reboot entry", `
+contextmenu:
+- name: Reboot
+  createProcess: "%WINDIR%\\System32\\shutdown.exe"
+  args:
+  - "/r"
+  - "/t 0" # now
+  hidden: true
+`, `
+contextmenu:
+- name: Reboot
+  createProcess: "%WINDIR%\\System32\\shutdown.exe"
+  args:
+  - "/r"
+  - "/t"
+  - "0" # now
+  hidden: true
+`},
+		{"hotkey and plain style", `
+hotkey:
+- buttons: WIN+END
+  openProcess: C:/Windows/System32/SHUTDOWN
+  args:
+    - /s   /t  30
+    - /f
+`, `
+hotkey:
+- buttons: WIN+END
+  openProcess: C:/Windows/System32/SHUTDOWN
+  args:
+    - /s
+    - /t
+    - "30"
+    - /f
+`},
+		{"taskbar menu in an overlay", `
+overlay:
+  user:
+    admin:
+      taskbar:
+        contextmenu:
+        - name: Logoff
+          shellExecute: shutdown
+          args: ["/l /f"]
+`, `
+overlay:
+  user:
+    admin:
+      taskbar:
+        contextmenu:
+          - name: Logoff
+            shellExecute: shutdown
+            args: ["/l", "/f"]
+`}, // a flow sequence is written from the node tree
+		{"other programs stay", `
+contextmenu:
+- name: Echo
+  openProcess: cmd.exe
+  args:
+  - /c
+  - echo a b
+- name: Shutdown tool
+  openProcess: shutdown-tool.exe
+  args:
+  - "/t 0"
+`, `
+contextmenu:
+- name: Echo
+  openProcess: cmd.exe
+  args:
+  - /c
+  - echo a b
+- name: Shutdown tool
+  openProcess: shutdown-tool.exe
+  args:
+  - "/t 0"
+`},
+	},
+	3: {
+		{"batch and PowerShell arguments", `
+contextmenu:
+- name: List
+  openProcess: cmd.exe
+  args:
+  - /c
+  - for %%i in (*) do echo %%i # a loop
+  - "%WINDIR%"
+- name: PID
+  shellExecute: powershell.exe
+  args: ['echo $$', "100%"]
+`, `
+contextmenu:
+- name: List
+  openProcess: cmd.exe
+  args:
+  - /c
+  - for %%%%i in (*) do echo %%%%i # a loop
+  - "%WINDIR%"
+- name: PID
+  shellExecute: powershell.exe
+  args: ['echo $$$$', "100%"]
+`},
+		{"hotkey and taskbar menu in an overlay", `
+hotkey:
+- buttons: WIN+P
+  openProcess: pwsh.exe
+  args: ["-c", "$$$"]
+overlay:
+  host:
+    laptop:
+      taskbar:
+        contextmenu:
+        - name: Echo
+          openProcess: cmd.exe
+          args:
+          - /c echo %%
+`, `
+hotkey:
+- buttons: WIN+P
+  openProcess: pwsh.exe
+  args: ["-c", "$$$$$"]
+overlay:
+  host:
+    laptop:
+      taskbar:
+        contextmenu:
+        - name: Echo
+          openProcess: cmd.exe
+          args:
+          - /c echo %%%%
+`},
+	},
+}
+
+// yamlValue decodes a document for the comparison of the node trees
+func yamlValue(t *testing.T, content []byte) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := yaml.Unmarshal(content, &v); err != nil {
+		t.Fatalf("%v in\n%s", err, content)
+	}
+	return v
+}
+
+func TestMigrations(t *testing.T) {
+	if len(migrations) != configVersion-1 {
+		t.Fatalf("%d migrations for version %d", len(migrations), configVersion)
+	}
+	for v := 1; v < configVersion; v++ {
+		if len(migrationTests[v]) == 0 {
+			t.Errorf("no test for the migration from version %d", v)
+		}
+		for _, tt := range migrationTests[v] {
+			t.Run(strconv.Itoa(v)+" "+tt.name, func(t *testing.T) {
+				// the leading newline is only for the readability of the table
+				before, after := strings.TrimPrefix(tt.before, "\n"), strings.TrimPrefix(tt.after, "\n")
+				f, errs := newConfigFile("config.yaml", []byte(before))
+				if errs != nil {
+					t.Fatal(errs)
+				}
+				for _, doc := range configDocuments(f.root.Content[0]) {
+					if err := migrations[v-1](f, doc); err != nil {
+						t.Fatal(err)
+					}
+				}
+				got, err := f.Bytes()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != after {
+					t.Errorf("got\n%s\nwant\n%s", got, after)
+				}
+				if !reflect.DeepEqual(yamlValue(t, got), yamlValue(t, []byte(after))) {
+					t.Errorf("the node tree differs from\n%s", after)
+				}
+			})
+		}
+	}
+}
+
+func TestMigrateConfig(t *testing.T) {
+	f, errs := newConfigFile("config.yaml", []byte("# my config\ntaskbar:\n  bgcolor: {r: 1, g: 2, b: 3, a: 0}\n"))
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	from, err := MigrateConfig(f)
+	if err != nil || from != 1 {
+		t.Fatalf("got %d, %v", from, err)
+	}
+	got, err := f.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "version: " + strconv.Itoa(configVersion) + "\n# my config\ntaskbar:\n  bgcolor: {r: 1, g: 2, b: 3}\n"
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if errs := ValidateConfig(got); errs != nil {
+		t.Errorf("the migrated config is not valid: %v", errs)
+	}
+}
+
+// a file at configVersion is not changed, also not by a second migration
+func TestMigrateConfigIdempotent(t *testing.T) {
+	content := "version: " + strconv.Itoa(configVersion) + "\ntaskbar:\n  bgcolor: {r: 1, g: 2, b: 3, a: 0}\n"
+	for round := 0; round < 2; round++ {
+		f, errs := newConfigFile("config.yaml", []byte(content))
+		if errs != nil {
+			t.Fatal(errs)
+		}
+		from, err := MigrateConfig(f)
+		if err != nil || from != configVersion {
+			t.Fatalf("round %d: got %d, %v", round, from, err)
+		}
+		got, err := f.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("round %d: got\n%s\nwant\n%s", round, got, content)
+		}
+		content = string(got)
+	}
+}
+
+func TestMigrateConfigVersion(t *testing.T) {
+	for _, tt := range []struct {
+		content string
+		from    int
+		err     bool
+	}{
+		{"", configVersion, false},
+		{"taskbar: {}\n", 1, false},
+		{"version: 1\n", 1, false},
+		{"version: 0\n", 0, true},
+		{"version: two\n", 0, true},
+		{"version: " + strconv.Itoa(configVersion+1) + "\n", configVersion + 1, true},
+	} {
+		f, errs := newConfigFile("config.yaml", []byte(tt.content))
+		if errs != nil {
+			t.Fatal(errs)
+		}
+		from, err := MigrateConfig(f)
+		if from != tt.from || (err != nil) != tt.err {
+			t.Errorf("%q: got %d, %v", tt.content, from, err)
+		}
+	}
+}
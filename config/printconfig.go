@@ -0,0 +1,50 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PrintConfig writes the effective config for -print-config. Values that
+// come from the defaults are marked, in yaml with a comment and in json
+// with the list "_defaults".
+func PrintConfig(w io.Writer, c *Config, format string) error {
+	var doc yaml.Node
+	if err := doc.Encode(c); err != nil {
+		return err
+	}
+
+	switch strings.ToLower(format) {
+	case "yaml", "yml":
+		for _, path := range c.defaults {
+			if n := lookupNode(&doc, strings.Split(path, ".")...); n != nil {
+				n.LineComment = "default"
+			}
+		}
+		doc.HeadComment = "effective configuration, read from:\n" + strings.Join(c.files, "\n")
+
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(&doc); err != nil {
+			return err
+		}
+		return enc.Close()
+
+	case "json":
+		var m map[string]interface{}
+		if err := doc.Decode(&m); err != nil {
+			return err
+		}
+		m["_files"] = c.files
+		m["_defaults"] = c.defaults
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(m)
+	}
+	return fmt.Errorf("unknown format %q, possible values: yaml, json", format)
+}
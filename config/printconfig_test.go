@@ -0,0 +1,137 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const printConfigFile = `version: 4
+taskbar:
+  height: 40
+  bgcolor: "#102030"
+contextmenu:
+- name: Explorer
+  openProcess: explorer.exe
+`
+
+const printConfigYAML = `# effective configuration, read from:
+# FILE
+version: 4
+desktop:
+  contextmenu:
+    darkMode: false
+    addDebugEntry: false
+taskbar:
+  fontFamily: Segoe UI # default
+  fontSize: 9 # default
+  position: bottom # default
+  iconPosition: left # default
+  height: 40
+  button:
+    size:
+      width: 160 # default
+      height: 30 # default
+    bgcolor: '#000000' # default
+    textcolor: '#000000' # default
+  bgcolor: '#102030'
+contextmenu:
+  - name: Explorer
+    openProcess: explorer.exe
+hotkey: []
+startup:
+  parallel: 2 # default
+  settle: 3s # default
+`
+
+const printConfigJSON = `{
+  "_defaults": [
+    "startup.parallel",
+    "startup.settle",
+    "taskbar.button.bgcolor",
+    "taskbar.button.size.height",
+    "taskbar.button.size.width",
+    "taskbar.button.textcolor",
+    "taskbar.fontFamily",
+    "taskbar.fontSize",
+    "taskbar.iconPosition",
+    "taskbar.position"
+  ],
+  "_files": [
+    "FILE"
+  ],
+  "contextmenu": [
+    {
+      "name": "Explorer",
+      "openProcess": "explorer.exe"
+    }
+  ],
+  "desktop": {
+    "contextmenu": {
+      "addDebugEntry": false,
+      "darkMode": false
+    }
+  },
+  "hotkey": [],
+  "startup": {
+    "parallel": 2,
+    "settle": "3s"
+  },
+  "taskbar": {
+    "bgcolor": "#102030",
+    "button": {
+      "bgcolor": "#000000",
+      "size": {
+        "height": 30,
+        "width": 160
+      },
+      "textcolor": "#000000"
+    },
+    "fontFamily": "Segoe UI",
+    "fontSize": 9,
+    "height": 40,
+    "iconPosition": "left",
+    "position": "bottom"
+  },
+  "version": 4
+}
+`
+
+func TestPrintConfig(t *testing.T) {
+	file := writeConfig(t, "config.yaml", printConfigFile)
+	c, err := ReadConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		format, want string
+	}{
+		{"yaml", printConfigYAML},
+		{"YML", printConfigYAML},
+		{"json", printConfigJSON},
+	} {
+		var buf bytes.Buffer
+		if err := PrintConfig(&buf, c, tt.format); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		name := file
+		if tt.format == "json" {
+			// a backslash of a Windows path is escaped in json
+			name = strings.ReplaceAll(file, `\`, `\\`)
+		}
+		if want := strings.ReplaceAll(tt.want, "FILE", name); buf.String() != want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.format, buf.String(), want)
+		}
+	}
+}
+
+func TestPrintConfigUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	err := PrintConfig(&buf, new(Config), "toml")
+	if err == nil || err.Error() != `unknown format "toml", possible values: yaml, json` {
+		t.Errorf("got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %q", buf.String())
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// QuoteArg quotes an argument so CommandLineToArgvW (and the C runtime)
+// reads it back unchanged. Backslashes are only special in front of a quote.
+// https://learn.microsoft.com/en-us/cpp/c-language/parsing-c-command-line-arguments
+func QuoteArg(arg string) string {
+	if arg == "" {
+		return `""`
+	}
+	if !strings.ContainsAny(arg, " \t\n\v\"") {
+		return arg
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	backslashes := 0
+	for i := 0; i < len(arg); i++ {
+		switch c := arg[i]; c {
+		case '\\':
+			backslashes++
+		case '"':
+			// 2n+1 backslashes are n backslashes and a literal quote
+			b.WriteString(strings.Repeat(`\`, 2*backslashes+1))
+			b.WriteByte('"')
+			backslashes = 0
+		default:
+			b.WriteString(strings.Repeat(`\`, backslashes))
+			b.WriteByte(c)
+			backslashes = 0
+		}
+	}
+	// the closing quote follows, so the trailing backslashes are doubled
+	b.WriteString(strings.Repeat(`\`, 2*backslashes))
+	b.WriteByte('"')
+	return b.String()
+}
+
+// JoinArgs builds the parameters of a command line from args
+func JoinArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = QuoteArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// SplitArgs splits the parameters of a command line like the C runtime,
+// the reverse of JoinArgs
+func SplitArgs(s string) []string {
+	var (
+		args    []string
+		arg     strings.Builder
+		inArg   bool // also true for ""
+		quoted  bool
+		slashes int
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			slashes++
+			inArg = true
+			continue
+		case c == '"':
+			arg.WriteString(strings.Repeat(`\`, slashes/2))
+			if slashes%2 == 1 {
+				arg.WriteByte('"')
+			} else if quoted && i+1 < len(s) && s[i+1] == '"' {
+				// "" in quotes is a literal quote
+				arg.WriteByte('"')
+				i++
+			} else {
+				quoted = !quoted
+			}
+			inArg = true
+		case (c == ' ' || c == '\t') && !quoted:
+			arg.WriteString(strings.Repeat(`\`, slashes))
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteString(strings.Repeat(`\`, slashes))
+			arg.WriteByte(c)
+			inArg = true
+		}
+		slashes = 0
+	}
+	arg.WriteString(strings.Repeat(`\`, slashes))
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args
+}
+
+// SplitCommandLine splits a command line of the registry like a Run value
+// into the program and its parameters. An unquoted program with spaces is
+// searched like CreateProcess does, the shortest path that exists wins:
+// C:\Program Files\App\app.exe -x tries C:\Program(.exe) first.
+// https://learn.microsoft.com/en-us/windows/win32/api/processthreadsapi/nf-processthreadsapi-createprocessw
+func SplitCommandLine(cmd string, exists func(file string) bool) (program, params string) {
+	cmd = strings.TrimSpace(cmd)
+	if strings.HasPrefix(cmd, `"`) {
+		if end := strings.IndexByte(cmd[1:], '"'); end >= 0 {
+			return cmd[1 : end+1], strings.TrimSpace(cmd[end+2:])
+		}
+		return strings.Trim(cmd, `"`), ""
+	}
+
+	for i := 0; i <= len(cmd); i++ {
+		if i < len(cmd) && cmd[i] != ' ' && cmd[i] != '\t' {
+			continue
+		}
+		candidate := cmd[:i]
+		if exists(candidate) {
+			return candidate, strings.TrimSpace(cmd[i:])
+		}
+		if filepath.Ext(candidate) == "" && exists(candidate+".exe") {
+			return candidate + ".exe", strings.TrimSpace(cmd[i:])
+		}
+	}
+	if i := strings.IndexAny(cmd, " \t"); i >= 0 {
+		return cmd[:i], strings.TrimSpace(cmd[i:])
+	}
+	return cmd, ""
+}
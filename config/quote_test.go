@@ -0,0 +1,175 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuoteArg(t *testing.T) {
+	for _, tt := range []struct {
+		arg, want string
+	}{
+		{"", `""`},
+		{"plain", "plain"},
+		{"/t", "/t"},
+		{"/t 0", `"/t 0"`},
+		{`C:\Program Files\App`, `"C:\Program Files\App"`},
+		{`C:\dir\`, `C:\dir\`},               // no quotes, the backslash stays single
+		{`C:\my dir\`, `"C:\my dir\\"`},      // doubled before the closing quote
+		{`C:\my dir\\`, `"C:\my dir\\\\"`},   // every one of them
+		{`say "hi"`, `"say \"hi\""`},         // embedded quotes
+		{`a\"b`, `"a\\\"b"`},                 // backslash before a quote
+		{`"`, `"\""`},                        // only a quote
+		{"tab\there", "\"tab\there\""},       // other whitespace
+		{`\\server\share`, `\\server\share`}, // leading backslashes
+		{`\\server\my share`, `"\\server\my share"`},
+	} {
+		if got := QuoteArg(tt.arg); got != tt.want {
+			t.Errorf("QuoteArg(%q): got %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestJoinArgs(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"/r", "/t", "0"}, "/r /t 0"},
+		{[]string{"/r", "/t 0"}, `/r "/t 0"`}, // one argument stays one
+		{[]string{"", "a", ""}, `"" a ""`},
+		{[]string{`C:\my dir\`, "-x"}, `"C:\my dir\\" -x`},
+	} {
+		if got := JoinArgs(tt.args); got != tt.want {
+			t.Errorf("JoinArgs(%q): got %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"/r /t 0", []string{"/r", "/t", "0"}},
+		{`/r "/t 0"`, []string{"/r", "/t 0"}},
+		{"a\t b", []string{"a", "b"}},
+		{`"" a ""`, []string{"", "a", ""}},
+		{`"a b"c`, []string{"a bc"}},
+		{`C:\dir\ x`, []string{`C:\dir\`, "x"}},
+		{`"C:\my dir\\" -x`, []string{`C:\my dir\`, "-x"}},
+		{`a\\\"b`, []string{`a\"b`}},
+		{`a\\\\"b c"`, []string{`a\\b c`}},
+		{`"say \"hi\""`, []string{`say "hi"`}},
+		{`"a""b"`, []string{`a"b`}},
+		{`"unclosed arg`, []string{"unclosed arg"}},
+		{`trailing\`, []string{`trailing\`}},
+	} {
+		if got := SplitArgs(tt.s); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitArgs(%s): got %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+// SplitArgs reads back what JoinArgs wrote
+func TestJoinSplitArgs(t *testing.T) {
+	for _, args := range [][]string{
+		{"/r", "/t 0"},
+		{"", "", "x"},
+		{`C:\my dir\`, `C:\dir\\`, `\`},
+		{`"`, `\"`, `a "b" c`, `a\\"b`},
+		{"tab\tand space", "new\nline"},
+	} {
+		if got := SplitArgs(JoinArgs(args)); !reflect.DeepEqual(got, args) {
+			t.Errorf("%q: got %q after %s", args, got, JoinArgs(args))
+		}
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	files := map[string]bool{
+		`C:\Program Files\App\app.exe`: true,
+		`C:\Tools\tool.exe`:            true,
+		`C:\Program`:                   false,
+	}
+	exists := func(file string) bool { return files[file] }
+	for _, tt := range []struct {
+		cmd, program, params string
+	}{
+		{"", "", ""},
+		{"/t 0", "/t", "0"},
+		{`"C:\Program Files\App\app.exe" /t 0`, `C:\Program Files\App\app.exe`, "/t 0"},
+		{`"C:\Program Files\App\app.exe"`, `C:\Program Files\App\app.exe`, ""},
+		{`"C:\unclosed\app.exe`, `C:\unclosed\app.exe`, ""},
+		{`""`, "", ""},
+		{`C:\Program Files\App\app.exe -x "a b"`, `C:\Program Files\App\app.exe`, `-x "a b"`},
+		{`C:\Program Files\App\app -x`, `C:\Program Files\App\app.exe`, "-x"},
+		{`C:\Tools\tool`, `C:\Tools\tool.exe`, ""},
+		{`C:\Missing\x.exe -y`, `C:\Missing\x.exe`, "-y"},
+		{"  spaced.exe   a  ", "spaced.exe", "a"},
+	} {
+		program, params := SplitCommandLine(tt.cmd, exists)
+		if program != tt.program || params != tt.params {
+			t.Errorf("SplitCommandLine(%s): got %q, %q, want %q, %q", tt.cmd, program, params, tt.program, tt.params)
+		}
+	}
+}
+
+// values of the Run keys as programs write them, the REG_EXPAND_SZ ones are
+// expanded before
+func TestSplitCommandLineRunValues(t *testing.T) {
+	files := map[string]bool{
+		`C:\Program Files\Microsoft OneDrive\OneDrive.exe`:           true,
+		`C:\Program Files\Realtek\Audio\HDA\RtkNGUI64.exe`:           true,
+		`C:\Program Files (x86)\Steam\steam.exe`:                     true,
+		`C:\Program Files\Windows Defender\MSASCuiL.exe`:             true,
+		`C:\Windows\system32\SecurityHealthSystray.exe`:              true,
+		`C:\Windows\System32\rundll32.exe`:                           true,
+		`C:\Program Files (x86)\Dropbox\Client\Dropbox.exe`:          true,
+		`C:\Users\a\AppData\Local\Discord\Update.exe`:                true,
+		`C:\Program Files\Common Files\Java\Java Update\jusched.exe`: true,
+	}
+	exists := func(file string) bool { return files[file] }
+	vars := map[string]string{
+		"windir":            `C:\Windows`,
+		"ProgramFiles":      `C:\Program Files`,
+		"ProgramFiles(x86)": `C:\Program Files (x86)`,
+		"LOCALAPPDATA":      `C:\Users\a\AppData\Local`,
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+	for _, tt := range []struct {
+		value, program, params string
+	}{
+		// quoted paths with spaces
+		{`"C:\Program Files\Microsoft OneDrive\OneDrive.exe" /background`, `C:\Program Files\Microsoft OneDrive\OneDrive.exe`, "/background"},
+		{`"C:\Program Files (x86)\Steam\steam.exe"   -silent`, `C:\Program Files (x86)\Steam\steam.exe`, "-silent"},
+		// unquoted paths with spaces
+		{`C:\Program Files\Realtek\Audio\HDA\RtkNGUI64.exe -s`, `C:\Program Files\Realtek\Audio\HDA\RtkNGUI64.exe`, "-s"},
+		{`C:\Program Files (x86)\Steam\steam.exe -silent`, `C:\Program Files (x86)\Steam\steam.exe`, "-silent"},
+		{`C:\Program Files\Common Files\Java\Java Update\jusched.exe`, `C:\Program Files\Common Files\Java\Java Update\jusched.exe`, ""},
+		// rundll32 lines
+		{`rundll32.exe C:\Windows\System32\shell32.dll,Control_RunDLL mmsys.cpl`, "rundll32.exe", `C:\Windows\System32\shell32.dll,Control_RunDLL mmsys.cpl`},
+		{`C:\Windows\System32\rundll32.exe "C:\Program Files\App\app.dll",Start -q`, `C:\Windows\System32\rundll32.exe`, `"C:\Program Files\App\app.dll",Start -q`},
+		{`C:\Windows\System32\rundll32 C:\Windows\System32\WWAHost.dll,Run`, `C:\Windows\System32\rundll32.exe`, `C:\Windows\System32\WWAHost.dll,Run`},
+		// environment variables
+		{`%windir%\system32\SecurityHealthSystray.exe`, `C:\Windows\system32\SecurityHealthSystray.exe`, ""},
+		{`"%ProgramFiles%\Windows Defender\MSASCuiL.exe"`, `C:\Program Files\Windows Defender\MSASCuiL.exe`, ""},
+		{`%ProgramFiles(x86)%\Dropbox\Client\Dropbox.exe /systemstartup`, `C:\Program Files (x86)\Dropbox\Client\Dropbox.exe`, "/systemstartup"},
+		{`%LOCALAPPDATA%\Discord\Update.exe --processStart Discord.exe`, `C:\Users\a\AppData\Local\Discord\Update.exe`, "--processStart Discord.exe"},
+	} {
+		value, warnings := ExpandVariables(tt.value, lookup)
+		if warnings != nil {
+			t.Errorf("%s: %q", tt.value, warnings)
+		}
+		program, params := SplitCommandLine(value, exists)
+		if program != tt.program || params != tt.params {
+			t.Errorf("%s: got %q, %q, want %q, %q", tt.value, program, params, tt.program, tt.params)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+// Service is a program that runs as long as the shell and is started again
+// when it exits
+type Service struct {
+	Name        string            `yaml:"name"`
+	Program     string            `yaml:"program"`
+	Args        []string          `yaml:"args,omitempty"`
+	CommandLine string            `yaml:"commandLine,omitempty"`
+	Workdir     string            `yaml:"workdir,omitempty"`
+	Env         map[string]string `yaml:"env,omitempty"`
+	Hidden      bool              `yaml:"hidden,omitempty"`
+	// onFailure (default) restarts only after an exit code other than 0,
+	// always after every exit, never runs the program once
+	Restart string `yaml:"restart,omitempty"`
+	// restarts in a row before it is given up, 0 is the default of 5
+	MaxRestarts int `yaml:"maxRestarts,omitempty"`
+	MergeRule   `yaml:",inline"`
+}
+
+// Launch returns how the program of the service is started
+func (svc *Service) Launch() Launch {
+	return Launch{
+		Kind:        "openProcess",
+		File:        svc.Program,
+		Args:        svc.Args,
+		CommandLine: svc.CommandLine,
+		Workdir:     svc.Workdir,
+		Env:         svc.Env,
+		Hidden:      svc.Hidden,
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+// StartupConfig is the startup section of the config, used with -startup
+type StartupConfig struct {
+	// programs that start at the same time
+	Parallel int `yaml:"parallel"`
+	// how long a program has for its start before the next one gets its slot
+	Settle Duration `yaml:"settle"`
+	// delay and priority of single programs
+	Items []StartupRule `yaml:"items,omitempty"`
+	// names or patterns of items that don't start
+	Exclude []string `yaml:"exclude,omitempty"`
+	// names or patterns of items that start even if they are excluded or
+	// disabled in the Task Manager
+	Include []string `yaml:"include,omitempty"`
+}
+
+// StartupRule changes when the startup items with a matching name start
+type StartupRule struct {
+	// value name of the Run key or file name in the startup folder, can be
+	// a pattern like "*OneDrive*"
+	Name     string   `yaml:"name"`
+	Delay    Duration `yaml:"delay,omitempty"`    // after GoShell started, or after the idle wait
+	Priority int      `yaml:"priority,omitempty"` // higher starts first
+	// starts after the computer is idle, with the other items of this stage
+	Idle      bool `yaml:"idle,omitempty"`
+	MergeRule `yaml:",inline"`
+}
@@ -0,0 +1,627 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigError is a single problem found in a config file.
+type ConfigError struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e ConfigError) Error() string {
+	var prefix string
+	if e.File != "" {
+		prefix = filepath.Base(e.File) + ", "
+	}
+	switch {
+	case e.Line == 0:
+		return prefix + e.Msg
+	case e.Column == 0:
+		return fmt.Sprintf("%sline %d: %s", prefix, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%sline %d, column %d: %s", prefix, e.Line, e.Column, e.Msg)
+}
+
+// ConfigErrors collects all problems of a config file.
+type ConfigErrors []ConfigError
+
+func (e ConfigErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	yamlLineRegEx   = regexp.MustCompile(`line (\d+): `)
+	unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+)
+
+// ValidateConfig checks a config document strictly and returns every
+// problem it finds: unknown keys, wrong types and invalid values.
+func ValidateConfig(content []byte) ConfigErrors {
+	root, errs := parseConfig(content)
+	if errs != nil {
+		return errs
+	}
+	return validateDocument(root)
+}
+
+// parseConfig returns the node tree of a config file or the syntax error
+func parseConfig(content []byte) (*yaml.Node, ConfigErrors) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		msg := strings.TrimPrefix(err.Error(), "yaml: ")
+		var line int
+		if m := yamlLineRegEx.FindStringSubmatch(msg); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			msg = strings.Replace(msg, m[0], "", 1)
+		}
+		return nil, ConfigErrors{{Line: line, Msg: msg}}
+	}
+	return &root, nil
+}
+
+// validateDocument is ValidateConfig for a parsed file
+func validateDocument(root *yaml.Node) ConfigErrors {
+	if len(root.Content) == 0 {
+		return nil // empty file
+	}
+
+	v := new(validator)
+	doc := root.Content[0]
+	if v.checkType(doc, reflect.TypeOf(Config{}), "") {
+		v.checkValues(doc, "")
+	}
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		if v.errs[i].Line != v.errs[j].Line {
+			return v.errs[i].Line < v.errs[j].Line
+		}
+		return v.errs[i].Column < v.errs[j].Column
+	})
+	return v.errs
+}
+
+type validator struct {
+	errs ConfigErrors
+}
+
+func (v *validator) errorf(n *yaml.Node, format string, a ...interface{}) {
+	v.errs = append(v.errs, ConfigError{Line: n.Line, Column: n.Column, Msg: fmt.Sprintf(format, a...)})
+}
+
+// checkType compares the node tree with the yaml tags of the Go type
+func (v *validator) checkType(n *yaml.Node, t reflect.Type, path string) bool {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.ShortTag() == "!!null" {
+		return true
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// types like Color check themselves
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		err := n.Decode(reflect.New(t).Interface())
+		if te, ok := err.(*yaml.TypeError); ok {
+			for _, msg := range te.Errors {
+				if m := yamlLineRegEx.FindStringSubmatch(msg); m != nil {
+					msg = strings.Replace(msg, m[0], "", 1)
+				}
+				v.errorf(n, "%s: %s", displayPath(path), msg)
+			}
+			return false
+		} else if err != nil {
+			v.errorf(n, "%s: %v", displayPath(path), err)
+			return false
+		}
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			v.errorf(n, "%s: expected a mapping", displayPath(path))
+			return false
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, val := n.Content[i], n.Content[i+1]
+			ft, ok := fields[k.Value]
+			if !ok {
+				if s := closestKey(k.Value, fields); s != "" {
+					v.errorf(k, "unknown key %q in %s, did you mean %q?", k.Value, displayPath(path), s)
+				} else {
+					v.errorf(k, "unknown key %q in %s", k.Value, displayPath(path))
+				}
+				continue
+			}
+			v.checkType(val, ft, joinPath(path, k.Value))
+		}
+
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			v.errorf(n, "%s: expected a list", displayPath(path))
+			return false
+		}
+		for i, item := range n.Content {
+			v.checkType(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			v.errorf(n, "%s: expected a mapping", displayPath(path))
+			return false
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v.checkType(n.Content[i+1], t.Elem(), joinPath(path, n.Content[i].Value))
+		}
+
+	case reflect.String:
+		if n.Kind != yaml.ScalarNode {
+			v.errorf(n, "%s: expected a string", displayPath(path))
+			return false
+		}
+
+	case reflect.Int:
+		if n.Kind != yaml.ScalarNode || n.ShortTag() != "!!int" {
+			v.errorf(n, "%s: expected a number, got %q", displayPath(path), n.Value)
+			return false
+		}
+
+	case reflect.Bool:
+		if n.Kind != yaml.ScalarNode || n.ShortTag() != "!!bool" {
+			v.errorf(n, "%s: expected true or false, got %q", displayPath(path), n.Value)
+			return false
+		}
+	}
+	return true
+}
+
+// checkValues validates the content of a document that has the right shape,
+// prefix is the path of an overlay
+func (v *validator) checkValues(doc *yaml.Node, prefix string) {
+	if n := lookupNode(doc, "taskbar", "position"); n != nil {
+		v.checkEnum(n, joinPath(prefix, "taskbar.position"), "top", "bottom")
+	}
+	if n := lookupNode(doc, "taskbar", "iconPosition"); n != nil {
+		v.checkEnum(n, joinPath(prefix, "taskbar.iconPosition"), "center", "left")
+	}
+
+	v.checkMenu(lookupNode(doc, "contextmenu"), joinPath(prefix, "contextmenu"))
+	v.checkMenu(lookupNode(doc, "taskbar", "contextmenu"), joinPath(prefix, "taskbar.contextmenu"))
+
+	if hotkeys := lookupNode(doc, "hotkey"); hotkeys != nil {
+		for i, item := range hotkeys.Content {
+			path := fmt.Sprintf("%s[%d]", joinPath(prefix, "hotkey"), i)
+			v.checkMergeRule(item, path)
+			buttons := lookupNode(item, "buttons")
+			if buttons == nil {
+				v.errorf(item, "%s: missing buttons", path)
+			} else if _, _, err := ParseHotkey(buttons.Value); err != nil {
+				v.errorf(buttons, "%s: %v", path, err)
+			}
+			v.checkAction(item, path)
+		}
+	}
+
+	v.checkVariables(lookupNode(doc, "variables"), joinPath(prefix, "variables"))
+	v.checkServices(lookupNode(doc, "services"), joinPath(prefix, "services"))
+	v.checkFileHandlers(lookupNode(doc, "fileHandlers"), joinPath(prefix, "fileHandlers"))
+	v.checkStartup(lookupNode(doc, "startup"), joinPath(prefix, "startup"))
+
+	if prefix != "" {
+		for _, key := range []string{"include", "overlay", "lock", "version"} {
+			if n := lookupNode(doc, key); n != nil {
+				v.errorf(n, "%s: %s is not possible in an overlay", prefix, key)
+			}
+		}
+		return
+	}
+	if locks := lookupNode(doc, "lock"); locks != nil {
+		for i, item := range locks.Content {
+			if _, ok := fieldByPath(reflect.ValueOf(&Config{}).Elem(), item.Value); !ok {
+				v.errorf(item, "lock[%d]: unknown key %q", i, item.Value)
+			}
+		}
+	}
+	for _, kind := range []string{"host", "user"} {
+		overlays := lookupNode(doc, "overlay", kind)
+		if overlays == nil || overlays.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(overlays.Content); i += 2 {
+			v.checkValues(overlays.Content[i+1], fmt.Sprintf("overlay.%s.%s", kind, overlays.Content[i].Value))
+		}
+	}
+}
+
+// checkMenu checks the entries of a contextmenu list
+func (v *validator) checkMenu(menu *yaml.Node, prefix string) {
+	if menu == nil {
+		return
+	}
+	for i, item := range menu.Content {
+		path := fmt.Sprintf("%s[%d]", prefix, i)
+		v.checkMergeRule(item, path)
+		name := lookupNode(item, "name")
+		if name == nil {
+			v.errorf(item, "%s: missing name", path)
+			continue
+		}
+		switch name.Value {
+		case "separator", "_", ".":
+			continue
+		}
+		if recent := lookupNode(item, "recent"); recent != nil {
+			if keys := append(append(actionKeys(item), keysOf(item, "path")...), keysOf(item, folderOptionKeys...)...); len(keys) != 0 {
+				v.errorf(item, "%s (%s): a recent entry can't have %s", path, name.Value, strings.Join(keys, ", "))
+			}
+			if n := lookupNode(recent, "size"); n != nil && strings.HasPrefix(n.Value, "-") {
+				v.errorf(n, "%s (%s).recent.size: can't be negative", path, name.Value)
+			}
+			continue
+		}
+		if lookupNode(item, "path") != nil {
+			if actions := actionKeys(item); len(actions) != 0 {
+				v.errorf(item, "%s (%s): a folder entry with path can't have %s", path, name.Value, strings.Join(actions, ", "))
+			}
+			v.checkFolderOptions(item, fmt.Sprintf("%s (%s)", path, name.Value))
+			continue
+		}
+		if keys := keysOf(item, folderOptionKeys...); len(keys) != 0 {
+			v.errorf(item, "%s (%s): path is missing for %s", path, name.Value, strings.Join(keys, ", "))
+		}
+		v.checkAction(item, fmt.Sprintf("%s (%s)", path, name.Value))
+	}
+}
+
+var folderOptionKeys = []string{"sort", "foldersFirst", "hideExtensions", "include", "exclude", "maxDepth", "flatten"}
+
+// checkFolderOptions checks the options of a contextmenu entry with path
+func (v *validator) checkFolderOptions(item *yaml.Node, path string) {
+	if n := lookupNode(item, "sort"); n != nil {
+		v.checkEnum(n, path+".sort", "name", "natural", "modified", "none")
+	}
+	for _, key := range []string{"include", "exclude"} {
+		if list := lookupNode(item, key); list != nil {
+			for i, pattern := range list.Content {
+				if _, err := filepath.Match(pattern.Value, ""); err != nil {
+					v.errorf(pattern, "%s.%s[%d]: %v", path, key, i, err)
+				}
+			}
+		}
+	}
+	if n := lookupNode(item, "maxDepth"); n != nil && strings.HasPrefix(n.Value, "-") {
+		v.errorf(n, "%s.maxDepth: can't be negative", path)
+	}
+}
+
+// checkVariables rejects names that differ only in case, a variable is
+// looked up case insensitive like the environment of Windows
+func (v *validator) checkVariables(variables *yaml.Node, path string) {
+	if variables == nil || variables.Kind != yaml.MappingNode {
+		return
+	}
+	names := map[string]string{}
+	for i := 0; i+1 < len(variables.Content); i += 2 {
+		k := variables.Content[i]
+		key := strings.ToLower(k.Value)
+		if first, ok := names[key]; ok {
+			v.errorf(k, "%s: %q is the same variable as %q, the names are case insensitive", path, k.Value, first)
+			continue
+		}
+		names[key] = k.Value
+	}
+}
+
+// checkServices checks the entries of the services list
+func (v *validator) checkServices(services *yaml.Node, prefix string) {
+	if services == nil {
+		return
+	}
+	names := map[string]bool{}
+	for i, item := range services.Content {
+		path := fmt.Sprintf("%s[%d]", prefix, i)
+		v.checkMergeRule(item, path)
+		if name := lookupNode(item, "name"); name == nil {
+			v.errorf(item, "%s: missing name", path)
+		} else if key := strings.ToLower(name.Value); names[key] && lookupNode(item, "merge") == nil {
+			v.errorf(name, "%s: the name %q is used twice", path, name.Value)
+		} else {
+			names[key] = true
+		}
+		if lookupNode(item, "program") == nil {
+			v.errorf(item, "%s: missing program", path)
+		}
+		if n := lookupNode(item, "commandLine"); n != nil && lookupNode(item, "args") != nil {
+			v.errorf(n, "%s: commandLine replaces args, only one of them is allowed", path)
+		}
+		if n := lookupNode(item, "restart"); n != nil {
+			v.checkEnum(n, path+".restart", "onFailure", "always", "never")
+		}
+		if n := lookupNode(item, "maxRestarts"); n != nil && strings.HasPrefix(n.Value, "-") {
+			v.errorf(n, "%s.maxRestarts: can't be negative", path)
+		}
+	}
+}
+
+// checkFileHandlers checks the entries of the fileHandlers list
+func (v *validator) checkFileHandlers(handlers *yaml.Node, prefix string) {
+	if handlers == nil {
+		return
+	}
+	for i, item := range handlers.Content {
+		path := fmt.Sprintf("%s[%d]", prefix, i)
+		v.checkMergeRule(item, path)
+		if match := lookupNode(item, "match"); match == nil {
+			v.errorf(item, "%s: missing match", path)
+		} else if _, err := filepath.Match(match.Value, ""); err != nil {
+			v.errorf(match, "%s.match: %v", path, err)
+		}
+		if n := lookupNode(item, "command"); n != nil {
+			v.errorf(n, "%s: command is not possible for a file", path)
+			continue
+		}
+		v.checkAction(item, path)
+	}
+}
+
+// checkStartup checks the parallel limit, the patterns and the rules of the
+// startup items
+func (v *validator) checkStartup(startup *yaml.Node, prefix string) {
+	if startup == nil {
+		return
+	}
+	if n := lookupNode(startup, "parallel"); n != nil && strings.HasPrefix(n.Value, "-") {
+		v.errorf(n, "%s.parallel: can't be negative", prefix)
+	}
+	for _, key := range []string{"exclude", "include"} {
+		if list := lookupNode(startup, key); list != nil {
+			for i, item := range list.Content {
+				if _, err := filepath.Match(item.Value, ""); err != nil {
+					v.errorf(item, "%s.%s[%d]: %v", prefix, key, i, err)
+				}
+			}
+		}
+	}
+	items := lookupNode(startup, "items")
+	if items == nil {
+		return
+	}
+	for i, item := range items.Content {
+		path := fmt.Sprintf("%s.items[%d]", prefix, i)
+		v.checkMergeRule(item, path)
+		if name := lookupNode(item, "name"); name == nil {
+			v.errorf(item, "%s: missing name", path)
+		} else if _, err := filepath.Match(name.Value, ""); err != nil {
+			v.errorf(name, "%s.name: %v", path, err)
+		}
+	}
+}
+
+func (v *validator) checkMergeRule(item *yaml.Node, path string) {
+	merge := lookupNode(item, "merge")
+	if merge == nil {
+		return
+	}
+	v.checkEnum(merge, path+".merge", "append", "replace", "insertBefore")
+	if strings.EqualFold(merge.Value, "insertBefore") && lookupNode(item, "target") == nil {
+		v.errorf(merge, "%s: insertBefore needs a target", path)
+	}
+}
+
+func (v *validator) checkEnum(n *yaml.Node, path string, values ...string) {
+	for _, val := range values {
+		if strings.EqualFold(n.Value, val) {
+			return
+		}
+	}
+	v.errorf(n, "%s: invalid value %q, possible values: %s", path, n.Value, strings.Join(values, ", "))
+}
+
+// an entry has to start exactly one program
+func (v *validator) checkAction(item *yaml.Node, path string) {
+	switch actions := actionKeys(item); len(actions) {
+	case 0:
+		v.errorf(item, "%s: one of shellExecute, createProcess, openProcess or command is required", path)
+	case 1:
+		if cmd := lookupNode(item, "command"); cmd != nil {
+			if _, ok := LookupCommand(cmd.Value); !ok {
+				v.errorf(cmd, "%s: unknown command %q, possible values: %s", path, cmd.Value, strings.Join(CommandNames(), ", "))
+			}
+		}
+	default:
+		v.errorf(item, "%s: only one of %s is allowed", path, strings.Join(actions, ", "))
+	}
+	if n := lookupNode(item, "commandLine"); n != nil {
+		switch {
+		case lookupNode(item, "args") != nil:
+			v.errorf(n, "%s: commandLine replaces args, only one of them is allowed", path)
+		case lookupNode(item, "command") != nil:
+			v.errorf(n, "%s: commandLine is not used by command, use args", path)
+		}
+	}
+
+	if n := lookupNode(item, "show"); n != nil {
+		v.checkEnum(n, path+".show", "normal", "minimized", "maximized", "hidden")
+		if lookupNode(item, "hidden") != nil {
+			v.errorf(n, "%s: show replaces hidden, only one of them is allowed", path)
+		}
+	}
+	if n := lookupNode(item, "verb"); n != nil {
+		v.checkEnum(n, path+".verb", "open", "runas", "edit", "print")
+		if env := lookupNode(item, "env"); env != nil && strings.EqualFold(n.Value, "runas") {
+			v.errorf(env, "%s: an elevated program doesn't get env, it starts with the environment of the administrator", path)
+		}
+	}
+	if n := lookupNode(item, "focusExisting", "titleRegex"); n != nil {
+		if _, err := regexp.Compile(n.Value); err != nil {
+			v.errorf(n, "%s.focusExisting.titleRegex: %v", path, err)
+		}
+	}
+	if lookupNode(item, "command") != nil {
+		for _, key := range []string{"workdir", "env", "show", "verb", "focusExisting"} {
+			if n := lookupNode(item, key); n != nil {
+				v.errorf(n, "%s: %s is not used by command", path, key)
+			}
+		}
+	}
+}
+
+func actionKeys(item *yaml.Node) (keys []string) {
+	for _, kind := range actionKinds {
+		keys = append(keys, keysOf(item, kind.key)...)
+	}
+	return
+}
+
+// keysOf returns the keys that item has
+func keysOf(item *yaml.Node, keys ...string) (found []string) {
+	for _, key := range keys {
+		if lookupNode(item, key) != nil {
+			found = append(found, key)
+		}
+	}
+	return
+}
+
+// lookupNode follows the keys through nested mappings
+func lookupNode(n *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if n.Kind == yaml.AliasNode {
+			n = n.Alias
+		}
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				next = n.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
+
+// yamlFields maps the yaml key names of a struct to the field types
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		name, inline := yamlKey(f)
+		switch {
+		case name == "-":
+		case inline:
+			for k, v := range yamlFields(f.Type) {
+				fields[k] = v
+			}
+		default:
+			fields[name] = f.Type
+		}
+	}
+	return fields
+}
+
+// yamlKey returns the key of a field like yaml.v3 does, "-" for a field
+// that is skipped
+func yamlKey(f reflect.StructField) (name string, inline bool) {
+	tag := strings.Split(f.Tag.Get("yaml"), ",")
+	if tag[0] == "-" {
+		return "-", false
+	}
+	for _, opt := range tag[1:] {
+		if opt == "inline" {
+			return "", true
+		}
+	}
+	if tag[0] == "" {
+		return strings.ToLower(f.Name), false
+	}
+	return tag[0], false
+}
+
+// closestKey finds a known key for typos like "fontsize" or "opneProcess"
+func closestKey(key string, fields map[string]reflect.Type) string {
+	best, bestDist := "", 3
+	for k := range fields {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+		if d := levenshtein(strings.ToLower(k), strings.ToLower(key)); d < bestDist || (d == bestDist && k < best) {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "config"
+	}
+	return path
+}
+
+// CheckConfigFile returns the problems of the file and its includes
+func CheckConfigFile(file string) ConfigErrors {
+	l := new(configLoader)
+	l.load(file)
+	return l.errs
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes content into a temporary config file
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestCheckConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []ConfigError // without File
+	}{
+		{"valid", `
+version: 2
+taskbar:
+  position: top
+  fontSize: 17
+hotkey:
+- buttons: WIN+E
+  openProcess: explorer.exe
+`, nil},
+		{"unknown key", `
+version: 2
+taskbar:
+  fontsize: 17
+  colour: red
+`, []ConfigError{
+			{Line: 4, Column: 3, Msg: `unknown key "fontsize" in taskbar, did you mean "fontSize"?`},
+			{Line: 5, Column: 3, Msg: `unknown key "colour" in taskbar`},
+		}},
+		{"wrong types", `
+version: 2
+taskbar:
+  height: high
+  button:
+    size: 160
+desktop:
+  contextmenu:
+    darkMode: yes please
+contextmenu: Notepad
+`, []ConfigError{
+			{Line: 4, Column: 11, Msg: `taskbar.height: expected a number, got "high"`},
+			{Line: 6, Column: 11, Msg: `taskbar.button.size: expected a mapping`},
+			{Line: 9, Column: 15, Msg: `desktop.contextmenu.darkMode: expected true or false, got "yes please"`},
+			{Line: 10, Column: 14, Msg: `contextmenu: expected a list`},
+		}},
+		{"bad enum values", `
+version: 2
+taskbar:
+  position: middle
+contextmenu:
+- name: editor
+  openProcess: notepad.exe
+  show: tiny
+services:
+- name: sync
+  program: sync.exe
+  restart: sometimes
+`, []ConfigError{
+			{Line: 4, Column: 13, Msg: `taskbar.position: invalid value "middle", possible values: top, bottom`},
+			{Line: 8, Column: 9, Msg: `contextmenu[0] (editor).show: invalid value "tiny", possible values: normal, minimized, maximized, hidden`},
+			{Line: 12, Column: 12, Msg: `services[0].restart: invalid value "sometimes", possible values: onFailure, always, never`},
+		}},
+		{"variables that differ in case", `
+version: 2
+variables:
+  tools: D:\Tools
+  Editor: notepad.exe
+  TOOLS: E:\Tools
+overlay:
+  user:
+    admin:
+      variables:
+        editor: a.exe
+        EDITOR: b.exe
+`, []ConfigError{
+			{Line: 6, Column: 3, Msg: `variables: "TOOLS" is the same variable as "tools", the names are case insensitive`},
+			{Line: 12, Column: 9, Msg: `overlay.user.admin.variables: "EDITOR" is the same variable as "editor", the names are case insensitive`},
+		}},
+		{"launchers", `
+version: 2
+contextmenu:
+- name: nothing
+  args: [-x]
+- name: both
+  shellExecute: a.exe
+  openProcess: b.exe
+- name: folder
+  path: [C:\Menu]
+- name: separator
+hotkey:
+- buttons: WIN+E
+- buttons: WIN+R
+  createProcess: a.exe
+  shellExecute: b.exe
+  openProcess: c.exe
+`, []ConfigError{
+			{Line: 4, Column: 3, Msg: `contextmenu[0] (nothing): one of shellExecute, createProcess, openProcess or command is required`},
+			{Line: 6, Column: 3, Msg: `contextmenu[1] (both): only one of shellExecute, openProcess is allowed`},
+			{Line: 13, Column: 3, Msg: `hotkey[0]: one of shellExecute, createProcess, openProcess or command is required`},
+			{Line: 14, Column: 3, Msg: `hotkey[1]: only one of shellExecute, createProcess, openProcess is allowed`},
+		}},
+		{"color values", `
+version: 2
+taskbar:
+  bgcolor: {r: 256, g: 0, b: 0}
+  button:
+    bgcolor: "rgb(1, 2, 300)"
+    textcolor: "#12345"
+`, []ConfigError{
+			{Line: 4, Column: 12, Msg: `taskbar.bgcolor: invalid color value r: "256", expected a number from 0 to 255`},
+			{Line: 6, Column: 14, Msg: `taskbar.button.bgcolor: invalid color "rgb(1, 2, 300)", "300" is not a number from 0 to 255`},
+			{Line: 7, Column: 16, Msg: `taskbar.button.textcolor: invalid color "#12345", expected #RRGGBB or #RRGGBBAA`},
+		}},
+		{"buttons", `
+version: 2
+hotkey:
+- buttons: WIN+
+  openProcess: a.exe
+- buttons: CTRL+NOPE
+  openProcess: b.exe
+- buttons: ""
+  openProcess: c.exe
+`, []ConfigError{
+			{Line: 4, Column: 12, Msg: `hotkey[0]: hotkey "WIN+": empty key`},
+			{Line: 6, Column: 12, Msg: `hotkey[1]: hotkey "CTRL+NOPE": unknown key "NOPE"`},
+			{Line: 8, Column: 12, Msg: `hotkey[2]: hotkey "": empty key`},
+		}},
+		{"syntax error", "version: 2\ntaskbar: [\n", []ConfigError{
+			{Line: 2, Msg: "did not find expected node content"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeConfig(t, "config.yaml", tt.content)
+			var want ConfigErrors
+			for _, e := range tt.want {
+				e.File = file
+				want = append(want, e)
+			}
+			if got := CheckConfigFile(file); !reflect.DeepEqual(got, want) {
+				t.Errorf("got\n%v\nwant\n%v", got, want)
+			}
+		})
+	}
+}
+
+func TestCheckConfigFileInclude(t *testing.T) {
+	file := writeConfig(t, "config.yaml", "version: 2\ninclude:\n- common.yaml\n")
+	common := filepath.Join(filepath.Dir(file), "common.yaml")
+	if err := os.WriteFile(common, []byte("version: 2\ntaskbar:\n  hight: 30\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := ConfigErrors{{File: common, Line: 3, Column: 3, Msg: `unknown key "hight" in taskbar, did you mean "height"?`}}
+	if got := CheckConfigFile(file); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCheckConfigFileMissing(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "missing.yaml")
+	errs := CheckConfigFile(file)
+	if len(errs) != 1 || errs[0].File != file || errs[0].Line != 0 {
+		t.Errorf("got %v", errs)
+	}
+}
+
+// the config.yaml of the repository has to stay valid
+func TestShippedConfig(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("..", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := ValidateConfig(content); errs != nil {
+		t.Errorf("config.yaml:\n%v", errs)
+	}
+}
+
+func TestConfigErrorString(t *testing.T) {
+	errs := ConfigErrors{
+		{File: filepath.Join("dir", "a.yaml"), Line: 3, Column: 5, Msg: "x"},
+		{Line: 2, Msg: "y"},
+		{Msg: "z"},
+	}
+	want := "a.yaml, line 3, column 5: x\nline 2: y\nz"
+	if got := errs.Error(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !strings.Contains(errs[0].Error(), "a.yaml") {
+		t.Errorf("%q doesn't name the file", errs[0].Error())
+	}
+}
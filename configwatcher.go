@@ -0,0 +1,108 @@
+//go:build windows
+
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// editors tend to save a file in several steps (truncate, write, rename)
+var configReloadDelay = time.Duration(time.Millisecond * 500)
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(file string) fileStamp {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: fi.ModTime(), size: fi.Size()}
+}
+
+// watchConfig calls onChange every time one of the files was changed on
+// disk until stop is called. The directories are watched instead of the
+// files so that editors which replace a file are noticed as well.
+func watchConfig(files []string, onChange func()) (stop func()) {
+	done, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		log.Println(err)
+		return func() {}
+	}
+
+	handles := []windows.Handle{done}
+	dirs := map[string]bool{}
+	for _, file := range files {
+		dir := strings.ToLower(filepath.Dir(file))
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+
+		// https://learn.microsoft.com/en-us/windows/win32/api/fileapi/nf-fileapi-findfirstchangenotificationw
+		h, err := windows.FindFirstChangeNotification(filepath.Dir(file), false, windows.FILE_NOTIFY_CHANGE_FILE_NAME|windows.FILE_NOTIFY_CHANGE_SIZE|windows.FILE_NOTIFY_CHANGE_LAST_WRITE)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		handles = append(handles, h)
+	}
+
+	stamps := make([]fileStamp, len(files))
+	for i, file := range files {
+		stamps[i] = statFile(file)
+	}
+
+	go func() {
+		defer func() {
+			for _, h := range handles[1:] {
+				windows.FindCloseChangeNotification(h)
+			}
+			windows.CloseHandle(done)
+		}()
+
+		var timer *time.Timer
+		for {
+			event, err := windows.WaitForMultipleObjects(handles, false, windows.INFINITE)
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			i := int(event - windows.WAIT_OBJECT_0)
+			if i == 0 {
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			}
+
+			for j, file := range files {
+				if stamp := statFile(file); stamp != stamps[j] {
+					stamps[j] = stamp
+					if timer == nil {
+						timer = time.AfterFunc(configReloadDelay, onChange)
+					} else {
+						timer.Reset(configReloadDelay)
+					}
+				}
+			}
+
+			if err := windows.FindNextChangeNotification(handles[i]); err != nil {
+				log.Println(err)
+				return
+			}
+		}
+	}()
+
+	return func() {
+		windows.SetEvent(done)
+	}
+}
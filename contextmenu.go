@@ -1,17 +1,17 @@
+//go:build windows
+
 package main
 
 import (
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"sort"
 	"strings"
-	"syscall"
+	"time"
 
+	"GoShell/config"
 	"github.com/leaanthony/winc"
 	"github.com/leaanthony/winc/w32"
-	lnk "github.com/parsiya/golnk"
 	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/registry"
 )
@@ -19,7 +19,7 @@ import (
 var FolderIconhBmp *winc.Bitmap
 
 func init() {
-	ic, err := winc.ExtractIcon(ResolveVariables("%SystemRoot%\\system32\\imageres.dll"), -4)
+	ic, err := winc.ExtractIcon(config.ResolveVariables("%SystemRoot%\\system32\\imageres.dll"), -4)
 	if err != nil {
 		log.Println(err)
 	}
@@ -30,21 +30,37 @@ func init() {
 	FolderIconhBmp = hBmp
 }
 
-var ContextmenuFunc = func(arg *winc.Event) {
-	data := arg.Data.(*winc.MouseContextData)
-	createCommandCall(data.Item.Command.Workdir, data.Item.Command.Filename)
-}
+func (s *shell) ContextMenu() *winc.MenuItem {
+	contextmenu := winc.NewContextMenu()
+	s.addMenuEntries(contextmenu, cfg.Contextmenu)
 
-var TaskmenuFunc = func(arg *winc.Event) {
-	data := arg.Data.(*winc.MouseContextData)
-	createCommandCall(data.Item.Command.Workdir, data.Item.Command.Filename)
+	if cfg.Desktop.Contextmenu.AddDebugEntry {
+		s.addMenuEntries(contextmenu, []config.Contextmenu{
+			{Name: "separator"},
+			{Name: "Exit GoShell", Action: config.Action{Command: "!quit"}},
+		})
+	}
+
+	return contextmenu
 }
 
-func (s *shell) ContextMenu() *winc.MenuItem {
+// TaskbarMenu is the context menu of the taskbar, Taskmgr if none is configured
+func (s *shell) TaskbarMenu() *winc.MenuItem {
+	entries := cfg.Taskbar.Contextmenu
+	if len(entries) == 0 {
+		entries = []config.Contextmenu{{Name: "Taskmgr", Action: config.Action{ShellExecute: config.ResolveVariables("%SystemRoot%\\system32\\Taskmgr.exe")}}}
+		entries[0].Icon.Filename = config.ResolveVariables("%SystemRoot%\\system32\\imageres.dll")
+		entries[0].Icon.Index = -150
+	}
+
 	contextmenu := winc.NewContextMenu()
+	s.addMenuEntries(contextmenu, entries)
+	return contextmenu
+}
 
-	for i := 0; i < len(config.Contextmenu); i++ {
-		menu := config.Contextmenu[i]
+func (s *shell) addMenuEntries(contextmenu *winc.MenuItem, entries []config.Contextmenu) {
+	for i := 0; i < len(entries); i++ {
+		menu := entries[i]
 		switch menu.Name {
 		case "separator", "_", ".":
 			contextmenu.AddSeparator()
@@ -53,39 +69,30 @@ func (s *shell) ContextMenu() *winc.MenuItem {
 			val := getDefaultValue(registry.CLASSES_ROOT, `CLSID\{20D04FE0-3AEA-1069-A2D8-08002B30309D}\DefaultIcon`)
 			commaSep := strings.Index(val, ",")
 			menu.Name = getMUINames(registry.CLASSES_ROOT, `CLSID\{20D04FE0-3AEA-1069-A2D8-08002B30309D}`, "Desktop")
-			menu.Icon.Filename = ResolveVariables(val[:commaSep])
+			menu.Icon.Filename = config.ResolveVariables(val[:commaSep])
 			menu.Icon.Index = StringToInt(val[commaSep+1:])
 		case "CLSID_Run":
 			val := getDefaultValue(registry.CLASSES_ROOT, `CLSID\{2559a1f3-21d7-11d4-bdaf-00c04f60b9f0}\DefaultIcon`)
 			commaSep := strings.Index(val, ",")
 			menu.Name = getMUINames(registry.CLASSES_ROOT, `CLSID\{2559a1f3-21d7-11d4-bdaf-00c04f60b9f0}`, "Run...")
-			menu.Icon.Filename = ResolveVariables(val[:commaSep])
+			menu.Icon.Filename = config.ResolveVariables(val[:commaSep])
 			menu.Icon.Index = StringToInt(val[commaSep+1:])
 		case "CLSID_RecycleBin":
 			val := getDefaultValue(registry.CLASSES_ROOT, `CLSID\{645FF040-5081-101B-9F08-00AA002F954E}\DefaultIcon`)
 			commaSep := strings.Index(val, ",")
 			menu.Name = getMUINames(registry.CLASSES_ROOT, `CLSID\{645FF040-5081-101B-9F08-00AA002F954E}`, "Recycle Bin")
-			menu.Icon.Filename = ResolveVariables(val[:commaSep])
+			menu.Icon.Filename = config.ResolveVariables(val[:commaSep])
 			menu.Icon.Index = StringToInt(val[commaSep+1:])
 		}
 
-		if len(menu.Path) != 0 {
-			AddSubMenu(contextmenu, menu.Name, menu.Path)
+		if menu.Recent != nil {
+			s.addRecentMenu(contextmenu, menu.Name, menu.Recent.SizeOrDefault())
+		} else if len(menu.Path) != 0 {
+			s.folderMenus = append(s.folderMenus, s.AddSubMenu(contextmenu, menu.Name, menu.Path, &entries[i].FolderOptions, 1))
 		} else {
-			AddItem(contextmenu, &menu)
+			s.AddItem(contextmenu, &menu)
 		}
 	}
-
-	if config.Desktop.Contextmenu.AddDebugEntry {
-		contextmenu.AddSeparator()
-
-		exitContextMenu := contextmenu.AddItem("Exit GoShell", winc.NoShortcut)
-		exitContextMenu.OnClick().Bind(func(_ *winc.Event) {
-			winc.Exit()
-		})
-	}
-
-	return contextmenu
 }
 
 func (s *shell) MiddleMenu() *winc.MenuItem {
@@ -111,14 +118,7 @@ func (s *shell) MiddleMenu() *winc.MenuItem {
 		}
 		m.Command.Hwnd = btn.hWnd
 		m.OnMClick().Bind(func(arg *winc.Event) {
-			hWnd := arg.Data.(*winc.MouseContextData).Item.Command.Hwnd
-			if !w32.IsWindowVisible(hWnd) {
-				w32.ShowWindow(w32.HWND(hWnd), w32.SW_SHOW)
-			}
-			if w32.IsIconic(hWnd) {
-				w32.ShowWindow(w32.HWND(hWnd), w32.SW_RESTORE)
-			}
-			w32.SetForegroundWindow(w32.HWND(w32.GetLastActivePopup(hWnd)))
+			ActivateWindow(arg.Data.(*winc.MouseContextData).Item.Command.Hwnd)
 		})
 	}
 
@@ -126,105 +126,32 @@ func (s *shell) MiddleMenu() *winc.MenuItem {
 }
 
 func (s *shell) Refresh() {
+	s.recentMenus = nil
+	s.folderMenus = nil
 	s.mainWindow.SetContextMenu(s.ContextMenu())
-}
-
-func createCommandCall(dir, file string) {
-	var prog string
-	var args []string
-	switch filepath.Ext(file) {
-	case ".ps1": // https://docs.microsoft.com/de-de/powershell/module/microsoft.powershell.core/about/about_powershell_exe
-		prog = "PowerShell.exe"
-		args = []string{"-NoLogo", "-NoProfile", "-ExecutionPolicy Bypass", "-File", filepath.Join(dir, file)}
-
-	default: // https://stackoverflow.com/a/12076082
-		prog = "rundll32.exe"
-		args = []string{"url.dll,FileProtocolHandler", filepath.Join(dir, file)}
+	if s.TaskbarWindow != nil {
+		s.TaskbarWindow.SetContextMenu(s.TaskbarMenu())
 	}
-	exec.Command(prog, args...).Start()
+	s.WatchFolders()
 }
 
-func shellExecute(argv0 string, args []string, hidden bool) syscall.Handle {
-	var lpFile *uint16
-	if len(args) != 0 {
-		lpFile, _ = syscall.UTF16PtrFromString(" " + strings.Join(args, " "))
-	} else {
-		lpFile, _ = syscall.UTF16PtrFromString(argv0)
-	}
-
-	var showCmd int32
-	if hidden {
-		showCmd = windows.SW_HIDE
-	} else {
-		showCmd = windows.SW_SHOWDEFAULT
+// WatchFolders updates the folder menus when something in their folders
+// changes, the menus are filled again when they open
+func (s *shell) WatchFolders() {
+	if s.stopFolderWatcher != nil {
+		s.stopFolderWatcher()
 	}
-
-	err := windows.ShellExecute(
-		0,
-		nil, // windows.StringToUTF16Ptr("open"), // windows.StringToUTF16Ptr("runas"),
-		lpFile,
-		nil,
-		nil,
-		showCmd,
-	)
-	if err != nil {
-		log.Printf("Return: %d, %v\n", err, err)
+	var dirs []string
+	for _, m := range s.folderMenus {
+		dirs = append(dirs, m.paths...)
 	}
-	return syscall.Handle(0)
-}
-
-// https://docs.microsoft.com/de-de/windows/win32/procthread/creating-processes
-// https://docs.microsoft.com/en-us/windows/win32/procthread/process-creation-flags
-func createProcess(argv0 string, args []string, hidden bool) syscall.ProcessInformation {
-	var lpCommandLine *uint16
-	if len(args) != 0 {
-		lpCommandLine, _ = syscall.UTF16PtrFromString(" " + strings.Join(args, " "))
-	}
-	lpApplicationName, _ := syscall.UTF16PtrFromString(argv0)
-
-	var startupInfo syscall.StartupInfo
-	startupInfo.Flags = w32.SW_SHOWNORMAL
-	var processInformation syscall.ProcessInformation
-
-	var creationFlags uint32
-	if hidden {
-		creationFlags = windows.NORMAL_PRIORITY_CLASS | windows.CREATE_NO_WINDOW | windows.CREATE_NEW_PROCESS_GROUP
-	} else {
-		creationFlags = windows.NORMAL_PRIORITY_CLASS | windows.CREATE_NEW_CONSOLE | windows.CREATE_NEW_PROCESS_GROUP
-	}
-
-	err := syscall.CreateProcess(
-		lpApplicationName,   // No module name (use command line)
-		lpCommandLine,       // Command line
-		nil,                 // Process handle not inheritable
-		nil,                 // Thread handle not inheritable
-		false,               // Set handle inheritance to FALSE
-		creationFlags,       // creation flags
-		nil,                 // Use parent's environment block
-		nil,                 // Use parent's starting directory
-		&startupInfo,        // Pointer to STARTUPINFO structure
-		&processInformation) // Pointer to PROCESS_INFORMATION structure
-
-	if err != nil {
-		log.Printf("Return: %d, %v\n", err, err)
-	}
-
-	return processInformation
-	// WaitForSingleObject(processInfo.hProcess, INFINITE);
-}
-
-// Start starts the specified command but does not wait for it to complete.
-func openProcess(file string, args []string, hidden bool) int {
-	cmd := exec.Command(file, args...)
-	if hidden {
-		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-	}
-	err := cmd.Start()
-	if err != nil {
-		log.Println(err)
-		w32.MessageBox(0, "openProcess", err.Error(), w32.MB_ICONERROR)
-	}
-	return cmd.Process.Pid
+	s.stopFolderWatcher = watchFolders(watchRoots(dirs), func(changed changedFolders) {
+		s.mainWindow.Invoke(func() {
+			for _, m := range s.folderMenus {
+				m.invalidate(&changed)
+			}
+		})
+	})
 }
 
 // https://docs.microsoft.com/en-us/windows/win32/api/shlobj_core/nf-shlobj_core-shgetfolderpatha
@@ -244,25 +171,6 @@ type DirValues struct {
 	Value string
 }
 
-func osReadDir(dirname string) (files, folders []string) {
-	fileinfos, err := os.ReadDir(dirname)
-	if err != nil {
-		log.Println(err)
-		w32.MessageBox(0, err.Error(), "ReadDir Error", w32.MB_ICONERROR)
-	}
-
-	for _, file := range fileinfos {
-		name := file.Name()
-		if file.IsDir() {
-			folders = append(folders, name)
-		} else if name != "Desktop.ini" && name != "desktop.ini" && name != "thumbs.db" {
-			files = append(files, name)
-		}
-	}
-
-	return
-}
-
 type IconContainer struct {
 	Pfad string
 	ID   int
@@ -312,133 +220,239 @@ func getMUINames(k registry.Key, path, alternativeName string) string {
 	return alternativeName
 }
 
-func AddSubMenu(contextmenu *winc.MenuItem, name string, targetfolder []string) {
-	var files [][]string
-	var folders = map[string][]string{}
-	for _, folder := range targetfolder {
-		subfiles, subfolder := osReadDir(folder)
-		for _, f := range subfiles {
-			files = append(files, []string{folder, f})
-		}
-		for _, f := range subfolder {
-			folders[f] = append(folders[f], filepath.Join(folder, f))
-
-		}
-	}
+// folderMenu is a submenu of the folders of a path entry, it is filled when
+// it opens and again when one of the folders changed since then
+type folderMenu struct {
+	folderCache
+	item *winc.MenuItem
+}
 
+// AddSubMenu adds an empty submenu for the folders, the content is read by
+// fillSubMenu when it opens
+func (s *shell) AddSubMenu(contextmenu *winc.MenuItem, name string, targetfolder []string, options *config.FolderOptions, depth int) *folderMenu {
 	submenu := contextmenu.AddSubMenu(name)
 	submenu.SetImage(FolderIconhBmp)
+	// without an item the arrow of the submenu is missing
+	submenu.AddItem("...", winc.NoShortcut).SetEnabled(false)
+
+	m := &folderMenu{item: submenu, folderCache: folderCache{paths: targetfolder, options: options, depth: depth}}
+	submenu.OnInitPopup().Bind(func(_ *winc.Event) {
+		if m.changed(folderModTime) {
+			s.fillSubMenu(m)
+		}
+	})
+	return m
+}
 
-	for name, paths := range folders {
-		AddSubMenu(submenu, name, paths)
+func (s *shell) fillSubMenu(m *folderMenu) {
+	// a message box would block the menu while it opens
+	entries, err := m.list(os.ReadDir, folderModTime)
+	if err != nil {
+		log.Println(err)
 	}
 
-	sort.Slice(files, func(i, j int) bool {
-		return files[i][1] < files[j][1]
-	})
+	submenu := m.item
+	submenu.Clear()
+	if err != nil {
+		submenu.AddItem("(error)", winc.NoShortcut).SetEnabled(false)
+	}
+	if len(entries) == 0 && err == nil {
+		submenu.AddItem("(empty)", winc.NoShortcut).SetEnabled(false)
+		return
+	}
 
-	for _, file := range files {
+	for _, entry := range entries {
+		if entry.Dir {
+			child := s.AddSubMenu(submenu, entry.Name, entry.Paths, m.options, m.depth+1)
+			m.children = append(m.children, &child.folderCache)
+			continue
+		}
 		var (
 			iconPath  string
 			iconIndex int32
 		)
+		path := entry.Paths[0]
+		name := entry.Label(m.options)
 
-		command := winc.Command{
-			Workdir:  file[0],
-			Filename: file[1],
-		}
-
-		if strings.ToLower(filepath.Ext(file[1])) == ".lnk" {
-			Lnk, err := lnk.File(filepath.Join(file[0], file[1]))
+		if strings.EqualFold(filepath.Ext(path), ".lnk") {
+			sc, err := ReadShortcut(path, ExpandEnvironment)
 			if err != nil {
-				panic(err)
-			}
-
-			if Lnk.StringData.IconLocation != "" {
-				iconPath = Lnk.StringData.IconLocation
-			}
-			if Lnk.Header.IconIndex != 0 {
-				iconIndex = Lnk.Header.IconIndex
-			}
-			if Lnk.LinkInfo.LocalBasePath != "" {
-				command.Filename = Lnk.LinkInfo.LocalBasePath
-			}
-			if Lnk.StringData.WorkingDir != "" {
-				command.Workdir = Lnk.StringData.WorkingDir
-			}
-			if Lnk.StringData.CommandLineArguments != "" {
-				command.Arguments = []string{Lnk.StringData.CommandLineArguments}
+				log.Println(err)
 			}
+			iconPath, iconIndex = sc.Icon, sc.IconIndex
 		}
 
 		var item *winc.MenuItem
 		if iconIndex == 0 && iconPath == "" {
-			item = submenu.AddItem(fileNameWithoutExt(file[1]), winc.NoShortcut)
-			hI := winc.GetIcon(filepath.Join(file[0], file[1]))
-			if hI != 0 {
-				ic, err := winc.NewIconFromHICONForDPI(hI, 96)
-				if err == nil {
-					hBmp, err := winc.NewBitmapFromIconForDPI(ic, w32.Size{Width: 16, Height: 16}, 96)
-					if err != nil {
-						log.Println(err)
-					}
-					item.SetImage(hBmp)
-				} else {
-					panic(err)
-				}
+			item = submenu.AddItem(name, winc.NoShortcut)
+			if hI := winc.GetIcon(path); hI != 0 {
+				item.SetImage(fileBitmap(hI, path))
 			}
 		} else {
-			item = submenu.AddItemWithBitmap(fileNameWithoutExt(file[1]), winc.NoShortcut, winc.GetBitmap(iconPath, int(iconIndex)))
+			item = submenu.AddItemWithBitmap(name, winc.NoShortcut, winc.GetBitmap(iconPath, int(iconIndex)))
 		}
-		item.Command = command
-		item.OnClick().Bind(ContextmenuFunc)
+		item.OnClick().Bind(func(_ *winc.Event) {
+			if s.OpenMenuFile(path) {
+				s.AddRecentFile(name, path)
+			}
+		})
 	}
 }
 
-func AddItem(contextmenu *winc.MenuItem, menu *Contextmenu) {
+// fileBitmap is the menu image of the icon of a file, the generic document
+// icon if it can't be converted
+func fileBitmap(hIcon w32.HICON, path string) *winc.Bitmap {
+	ic, err := winc.NewIconFromHICONForDPI(hIcon, 96)
+	if err == nil {
+		var hBmp *winc.Bitmap
+		if hBmp, err = winc.NewBitmapFromIconForDPI(ic, w32.Size{Width: 16, Height: 16}, 96); err == nil {
+			return hBmp
+		}
+	}
+	log.Println(path, err)
+	return winc.GetBitmap(config.ResolveVariables("%SystemRoot%\\system32\\imageres.dll"), -2)
+}
+
+func (s *shell) AddItem(contextmenu *winc.MenuItem, menu *config.Contextmenu) {
 	var newMenu *winc.MenuItem
 
 	if menu.Icon.Filename != "" {
-		newMenu = contextmenu.AddItemWithBitmap(menu.Name, winc.NoShortcut, winc.GetBitmap(ResolveVariables(menu.Icon.Filename), menu.Icon.Index))
+		newMenu = contextmenu.AddItemWithBitmap(menu.Name, winc.NoShortcut, winc.GetBitmap(menu.Icon.Filename, menu.Icon.Index))
 	} else {
-		var path string
-		switch {
-		case menu.ShellExecute != "":
-			path = menu.ShellExecute
-		case menu.CreateProcess != "":
-			path = menu.CreateProcess
-		case menu.OpenProcess != "":
-			path = menu.OpenProcess
+		newMenu = addItemWithFileIcon(contextmenu, menu.Name, menu.File())
+	}
+
+	newMenu.OnClick().Bind(func(_ *winc.Event) {
+		s.Run(menu.Name, &menu.Action)
+	})
+}
+
+// addItemWithFileIcon adds an item with the icon of file, without one if
+// the file has none
+func addItemWithFileIcon(contextmenu *winc.MenuItem, name, file string) *winc.MenuItem {
+	hIcon := winc.GetIcon(file)
+	if hIcon == 0 {
+		return contextmenu.AddItem(name, winc.NoShortcut)
+	}
+	hBmp := winc.GetBitmapFromIcon(hIcon, w32.Size{Width: 16, Height: 16}, 96)
+	if hBmp.GetHBITMAP() == 0 {
+		return contextmenu.AddItem(name, winc.NoShortcut)
+	}
+	return contextmenu.AddItemWithBitmap(name, winc.NoShortcut, hBmp)
+}
+
+// recentMenu is a built recent submenu, it is filled again on every change
+type recentMenu struct {
+	item *winc.MenuItem
+	size int
+}
+
+// LoadRecent reads the history of the recent menus
+func (s *shell) LoadRecent() {
+	r, err := LoadRecentList(RecentFilePath())
+	if err != nil {
+		log.Println(err)
+	}
+	s.recent = r
+}
+
+// AddRecent records a launched action for the recent menus
+func (s *shell) AddRecent(name string, a *config.Action) {
+	size := recentSize(cfg)
+	l, ok := a.Launch()
+	if size == 0 || !ok || l.Kind == "command" {
+		return
+	}
+	if name == "" {
+		name = fileNameWithoutExt(filepath.Base(l.File))
+	}
+	s.recent.Add(RecentItem{Name: name, Action: *a}, time.Now(), size)
+	s.saveRecent()
+}
+
+// AddRecentFile records an opened file of a folder menu, it is opened with
+// OpenMenuFile again
+func (s *shell) AddRecentFile(name, file string) {
+	if size := recentSize(cfg); size != 0 {
+		s.recent.Add(RecentItem{Name: name, File: file}, time.Now(), size)
+		s.saveRecent()
+	}
+}
+
+func (s *shell) saveRecent() {
+	if err := s.recent.Save(); err != nil {
+		log.Println(err)
+	}
+	for _, m := range s.recentMenus {
+		m.item.Clear()
+		s.fillRecentMenu(m.item, m.size)
+	}
+}
+
+func (s *shell) addRecentMenu(contextmenu *winc.MenuItem, name string, size int) {
+	submenu := contextmenu.AddSubMenu(name)
+	submenu.SetImage(FolderIconhBmp)
+	s.fillRecentMenu(submenu, size)
+	s.recentMenus = append(s.recentMenus, recentMenu{item: submenu, size: size})
+}
+
+// fillRecentMenu adds the pinned items, the recent ones and the submenus to
+// pin and unpin them
+func (s *shell) fillRecentMenu(submenu *winc.MenuItem, size int) {
+	pinned, recent := s.recent.Menu(size)
+	for _, item := range pinned {
+		s.addRecentItem(submenu, item)
+	}
+	if len(pinned) != 0 && len(recent) != 0 {
+		submenu.AddSeparator()
+	}
+	for _, item := range recent {
+		s.addRecentItem(submenu, item)
+	}
+	if len(pinned) == 0 && len(recent) == 0 {
+		submenu.AddItem("(empty)", winc.NoShortcut).SetEnabled(false)
+		return
+	}
+
+	submenu.AddSeparator()
+	for _, pin := range []struct {
+		name  string
+		items []RecentItem
+	}{{"Pin", recent}, {"Unpin", pinned}} {
+		if len(pin.items) == 0 {
+			continue
 		}
-		hIcon := winc.GetIcon(ResolveVariables(path))
-		if hIcon == 0 {
-			newMenu = contextmenu.AddItem(menu.Name, winc.NoShortcut)
-		} else {
-			hBmp := winc.GetBitmapFromIcon(hIcon, w32.Size{Width: 16, Height: 16}, 96)
-			if hBmp.GetHBITMAP() == 0 {
-				newMenu = contextmenu.AddItem(menu.Name, winc.NoShortcut)
-			} else {
-				newMenu = contextmenu.AddItemWithBitmap(menu.Name, winc.NoShortcut, hBmp)
-			}
+		pinMenu := submenu.AddSubMenu(pin.name)
+		for _, item := range pin.items {
+			item, pinned := item, pin.name == "Pin"
+			pinMenu.AddItem(item.Name, winc.NoShortcut).OnClick().Bind(func(_ *winc.Event) {
+				s.recent.SetPinned(&item, pinned)
+				s.saveRecent()
+			})
 		}
 	}
-
-	switch {
-	case menu.ShellExecute != "":
-		newMenu.OnClick().Bind(func(_ *winc.Event) {
-			shellExecute(ResolveVariables(menu.ShellExecute), menu.Args, menu.Hidden)
-		})
-	case menu.CreateProcess != "":
-		newMenu.OnClick().Bind(func(_ *winc.Event) {
-			createProcess(ResolveVariables(menu.CreateProcess), menu.Args, menu.Hidden)
-		})
-	case menu.OpenProcess != "":
-		newMenu.OnClick().Bind(func(_ *winc.Event) {
-			openProcess(ResolveVariables(menu.OpenProcess), menu.Args, menu.Hidden)
+	if len(recent) != 0 {
+		submenu.AddItem("Clear", winc.NoShortcut).OnClick().Bind(func(_ *winc.Event) {
+			s.recent.Clear()
+			s.saveRecent()
 		})
 	}
 }
 
+// addRecentItem adds an item of the recent menu, a file runs like in the
+// folder menu it came from
+func (s *shell) addRecentItem(submenu *winc.MenuItem, item RecentItem) {
+	if item.File == "" {
+		s.AddItem(submenu, &config.Contextmenu{Name: item.Name, Action: item.Action})
+		return
+	}
+	addItemWithFileIcon(submenu, item.Name, item.File).OnClick().Bind(func(_ *winc.Event) {
+		if s.OpenMenuFile(item.File) {
+			s.AddRecentFile(item.Name, item.File)
+		}
+	})
+}
+
 // func hIconForFilePath(filePath string) w32.HICON {
 // 	fPptr, _ := syscall.UTF16PtrFromString(filePath)
 // 	var shfi w32.SHFILEINFO
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"GoShell/config"
+)
+
+// windowInfo is what FocusExisting compares of a window
+type windowInfo struct {
+	hWnd  uintptr
+	exe   string
+	class string
+	title string
+}
+
+// focusMatcher returns the function that tests a window, without exe, class and
+// titleRegex the window has to belong to file
+func focusMatcher(f *config.FocusExisting, file string) (func(w windowInfo) bool, error) {
+	exe := f.Exe
+	if exe == "" && f.Class == "" && f.TitleRegex == "" {
+		exe = file
+	}
+	var title *regexp.Regexp
+	if f.TitleRegex != "" {
+		var err error
+		if title, err = regexp.Compile(f.TitleRegex); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(w windowInfo) bool {
+		if exe != "" && !sameExe(exe, w.exe) {
+			return false
+		}
+		if f.Class != "" && !strings.EqualFold(f.Class, w.class) {
+			return false
+		}
+		return title == nil || title.MatchString(w.title)
+	}, nil
+}
+
+// sameExe compares only the file name if exe has no directory
+func sameExe(exe, path string) bool {
+	if !strings.ContainsAny(exe, `\/`) {
+		path = filepath.Base(path)
+		if filepath.Ext(exe) == "" {
+			exe += filepath.Ext(path)
+		}
+	}
+	return strings.EqualFold(filepath.Clean(exe), filepath.Clean(path))
+}
+
+// nextWindow picks the window to activate from the matches: the first one,
+// or with cycle the one after the foreground window
+func nextWindow(matches []uintptr, foreground uintptr, cycle bool) uintptr {
+	if cycle {
+		for i, hWnd := range matches {
+			if hWnd == foreground {
+				return matches[(i+1)%len(matches)]
+			}
+		}
+	}
+	return matches[0]
+}
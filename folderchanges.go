@@ -0,0 +1,119 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// an installer creates its shortcuts one after another
+var folderReloadDelay = time.Duration(time.Second)
+
+// WaitForMultipleObjects waits for at most 64 handles, one of them stops the
+// watcher
+const maxWatchRoots = 63
+
+// changedFolders are the folders with a change since the last time the
+// folder menus were updated
+type changedFolders struct {
+	dirs  map[string]bool // lower case, a file or folder in it changed
+	trees map[string]bool // lower case, anything below could have changed
+}
+
+func (c *changedFolders) add(dir string) {
+	if c.dirs == nil {
+		c.dirs = map[string]bool{}
+	}
+	c.dirs[strings.ToLower(filepath.Clean(dir))] = true
+}
+
+func (c *changedFolders) addTree(root string) {
+	if c.trees == nil {
+		c.trees = map[string]bool{}
+	}
+	c.trees[strings.ToLower(filepath.Clean(root))] = true
+}
+
+func (c *changedFolders) empty() bool {
+	return len(c.dirs) == 0 && len(c.trees) == 0
+}
+
+// affects reports if the content of the folder dir changed
+func (c *changedFolders) affects(dir string) bool {
+	dir = strings.ToLower(filepath.Clean(dir))
+	if c.dirs[dir] {
+		return true
+	}
+	for root := range c.trees {
+		if dir == root || strings.HasPrefix(dir, strings.TrimSuffix(root, `\`)+`\`) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchRoots returns the folders to watch recursively, without the ones in
+// another folder of the list. Only the first maxWatchRoots are watched.
+func watchRoots(dirs []string) (roots []string) {
+	sorted := append([]string(nil), dirs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) < len(sorted[j]) })
+	var tree changedFolders
+	for _, dir := range sorted {
+		if dir == "" || tree.affects(dir) {
+			continue
+		}
+		if len(roots) == maxWatchRoots {
+			log.Println("too many folders to watch, not watched:", dir)
+			continue
+		}
+		tree.addTree(dir)
+		roots = append(roots, filepath.Clean(dir))
+	}
+	return
+}
+
+// folderChanges collects the changes and passes them to onChange once there
+// was no change for delay
+type folderChanges struct {
+	delay    time.Duration
+	onChange func(changed changedFolders)
+
+	mu      sync.Mutex
+	changed changedFolders
+	timer   *time.Timer
+}
+
+// record adds changes with f and starts the delay again
+func (c *folderChanges) record(f func(changed *changedFolders)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f(&c.changed)
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.delay, c.flush)
+	} else {
+		c.timer.Reset(c.delay)
+	}
+}
+
+func (c *folderChanges) flush() {
+	c.mu.Lock()
+	changed := c.changed
+	c.changed = changedFolders{}
+	c.mu.Unlock()
+	if !changed.empty() {
+		c.onChange(changed)
+	}
+}
+
+// stop drops the changes that weren't passed yet
+func (c *folderChanges) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.changed = changedFolders{}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChangedFoldersAffects(t *testing.T) {
+	var c changedFolders
+	c.add(`C:\Users\a\Start Menu\Programs`)
+	c.addTree(`C:\Tools\Menu`)
+	c.addTree(`D:\`)
+	for _, tt := range []struct {
+		dir  string
+		want bool
+	}{
+		{`C:\Users\a\Start Menu\Programs`, true},
+		{`c:\users\A\START MENU\programs`, true},
+		{`C:\Users\a\Start Menu\Programs\Sub`, false}, // only the folder itself
+		{`C:\Users\a\Start Menu`, false},
+		{`C:\Tools\Menu`, true},
+		{`C:\TOOLS\menu\Sub\Deeper`, true},
+		{`C:\Tools\MenuX`, false}, // a sibling with the same prefix
+		{`C:\Tools`, false},
+		{`D:\`, true},
+		{`D:\Anything\Below`, true},
+		{`E:\`, false},
+	} {
+		if got := c.affects(tt.dir); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestWatchRoots(t *testing.T) {
+	for _, tt := range []struct {
+		dirs, want []string
+	}{
+		{nil, nil},
+		{[]string{`C:\a\b`, `C:\a\bc`}, []string{`C:\a\b`, `C:\a\bc`}},
+		{[]string{`C:\a\b\c`, `C:\a\b`, `C:\a\b\d`}, []string{`C:\a\b`}},
+		{[]string{`C:\Menu\Sub`, `c:\menu`}, []string{`c:\menu`}},
+		{[]string{`C:\Menu`, `C:\MENU`}, []string{`C:\Menu`}},
+		{[]string{`D:\Tools`, `C:\`, `C:\Menu`, ""}, []string{`C:\`, `D:\Tools`}},
+	} {
+		if got := watchRoots(tt.dirs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.dirs, got, tt.want)
+		}
+	}
+}
+
+func TestWatchRootsLimit(t *testing.T) {
+	var dirs []string
+	for i := 0; i < maxWatchRoots+5; i++ {
+		dirs = append(dirs, fmt.Sprintf(`C:\Menu%03d`, i))
+	}
+	roots := watchRoots(dirs)
+	if len(roots) != maxWatchRoots || roots[0] != dirs[0] || roots[maxWatchRoots-1] != dirs[maxWatchRoots-1] {
+		t.Errorf("got %d roots: %q", len(roots), roots)
+	}
+}
+
+// the changes within the delay are passed at once
+func TestFolderChangesCoalesce(t *testing.T) {
+	calls := make(chan changedFolders, 10)
+	c := &folderChanges{delay: 50 * time.Millisecond, onChange: func(changed changedFolders) { calls <- changed }}
+	c.record(func(changed *changedFolders) { changed.add(`C:\a`) })
+	c.record(func(changed *changedFolders) { changed.add(`C:\b`) })
+	c.record(func(changed *changedFolders) { changed.addTree(`C:\c`) })
+
+	select {
+	case changed := <-calls:
+		if !changed.affects(`C:\a`) || !changed.affects(`C:\b`) || !changed.affects(`C:\c\d`) {
+			t.Errorf("got %+v", changed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change passed")
+	}
+	select {
+	case changed := <-calls:
+		t.Errorf("passed a second time: %+v", changed)
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	// stop drops what wasn't passed yet
+	c.record(func(changed *changedFolders) { changed.add(`C:\e`) })
+	c.stop()
+	select {
+	case changed := <-calls:
+		t.Errorf("passed after stop: %+v", changed)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"GoShell/config"
+)
+
+// folderEntry is a file or a folder of a folder menu
+type folderEntry struct {
+	Name string
+	Dir  bool
+	// the file, or the folders with this name in every path
+	Paths   []string
+	ModTime time.Time
+	// the folders that flatten replaced by this entry
+	Folded []string
+}
+
+// Label is the text of the menu item
+func (e *folderEntry) Label(o *config.FolderOptions) string {
+	if !e.Dir && o.HideExtensionsOrDefault() {
+		return fileNameWithoutExt(e.Name)
+	}
+	return e.Name
+}
+
+// hidden files of a folder that are never shown
+var folderMenuSkip = []string{"desktop.ini", "thumbs.db"}
+
+// listFolder returns the entries of the folders at depth (1 for the menu of
+// path) in their order. The folders are merged: folders with the same name
+// become one submenu, of files with the same name the one of the first path
+// is shown. The error is the first folder that couldn't be read.
+func listFolder(o *config.FolderOptions, paths []string, depth int, readDir func(string) ([]fs.DirEntry, error)) (entries []folderEntry, err error) {
+	entries, err = readFolders(o, paths, depth, readDir)
+	if o.Flatten {
+		for i := range entries {
+			entries[i] = flattenEntry(o, entries[i], depth, readDir)
+		}
+	}
+	sortEntries(o, entries)
+	return
+}
+
+// folderCache is what a folder menu was filled from, the menu is filled
+// again when it opens and one of the folders changed since then
+type folderCache struct {
+	paths   []string
+	options *config.FolderOptions
+	depth   int // 1 for the submenu of the path entry
+	// paths and the folders that were flattened into it
+	read     []string
+	modTimes []time.Time // of read when it was filled, nil before
+	children []*folderCache
+}
+
+// list reads the entries of the menu and remembers the folders it read, the
+// children are added by the caller
+func (c *folderCache) list(readDir func(string) ([]fs.DirEntry, error), modTime func(string) time.Time) ([]folderEntry, error) {
+	entries, err := listFolder(c.options, c.paths, c.depth, readDir)
+	c.read = append([]string(nil), c.paths...)
+	for _, entry := range entries {
+		c.read = append(c.read, entry.Folded...)
+	}
+	c.modTimes = make([]time.Time, len(c.read))
+	for i, folder := range c.read {
+		c.modTimes[i] = modTime(folder)
+	}
+	c.children = nil
+	return entries, err
+}
+
+// invalidate lets the menus of the changed folders be filled again when
+// they open
+func (c *folderCache) invalidate(changed *changedFolders) {
+	for _, path := range c.read {
+		if changed.affects(path) {
+			c.modTimes = nil
+			break
+		}
+	}
+	for _, child := range c.children {
+		child.invalidate(changed)
+	}
+}
+
+// changed reports if the folders were never read or an entry was added,
+// removed or renamed since then
+func (c *folderCache) changed(modTime func(string) time.Time) bool {
+	if c.modTimes == nil {
+		return true
+	}
+	for i, path := range c.read {
+		if !modTime(path).Equal(c.modTimes[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func folderModTime(path string) time.Time {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
+
+// readFolders returns the merged and filtered entries of the folders,
+// unsorted and without flatten
+func readFolders(o *config.FolderOptions, paths []string, depth int, readDir func(string) ([]fs.DirEntry, error)) (entries []folderEntry, err error) {
+	index := map[string]int{} // "d:" or "f:" + lower case name
+	for _, path := range paths {
+		list, e := readDir(path)
+		if e != nil && err == nil {
+			err = e
+		}
+		for _, de := range list {
+			entry := folderEntry{Name: de.Name(), Dir: de.IsDir(), Paths: []string{filepath.Join(path, de.Name())}}
+			if !showEntry(o, entry, depth) {
+				continue
+			}
+			if info, e := de.Info(); e == nil {
+				entry.ModTime = info.ModTime()
+			}
+			key := "f:" + strings.ToLower(entry.Name)
+			if entry.Dir {
+				key = "d:" + strings.ToLower(entry.Name)
+			}
+			if i, ok := index[key]; ok {
+				if entry.Dir {
+					entries[i].Paths = append(entries[i].Paths, entry.Paths...)
+					if entry.ModTime.After(entries[i].ModTime) {
+						entries[i].ModTime = entry.ModTime
+					}
+				}
+				continue
+			}
+			index[key] = len(entries)
+			entries = append(entries, entry)
+		}
+	}
+	return
+}
+
+// showEntry filters a single entry by the options
+func showEntry(o *config.FolderOptions, e folderEntry, depth int) bool {
+	name := strings.ToLower(e.Name)
+	for _, skip := range folderMenuSkip {
+		if name == skip {
+			return false
+		}
+	}
+	if e.Dir && o.MaxDepth > 0 && depth >= o.MaxDepth {
+		return false
+	}
+	if config.MatchNames(o.Exclude, e.Name) {
+		return false
+	}
+	return e.Dir || len(o.Include) == 0 || config.MatchNames(o.Include, e.Name)
+}
+
+// flattenEntry replaces a folder with a single entry by that entry. Only one
+// level is read, the folders below are flattened when their menu is filled.
+func flattenEntry(o *config.FolderOptions, e folderEntry, depth int, readDir func(string) ([]fs.DirEntry, error)) folderEntry {
+	if !e.Dir {
+		return e
+	}
+	children, _ := readFolders(o, e.Paths, depth+1, readDir)
+	if len(children) != 1 {
+		return e
+	}
+	child := children[0]
+	child.Folded = e.Paths
+	return child
+}
+
+func sortEntries(o *config.FolderOptions, entries []folderEntry) {
+	less := func(a, b *folderEntry) bool { return false }
+	switch strings.ToLower(o.Sort) {
+	case "", "name":
+		less = func(a, b *folderEntry) bool { return lessFold(a.Name, b.Name) }
+	case "natural":
+		less = func(a, b *folderEntry) bool { return lessNatural(a.Name, b.Name) }
+	case "modified":
+		less = func(a, b *folderEntry) bool {
+			if !a.ModTime.Equal(b.ModTime) {
+				return a.ModTime.After(b.ModTime)
+			}
+			return lessFold(a.Name, b.Name)
+		}
+	}
+	foldersFirst := o.FoldersFirstOrDefault()
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := &entries[i], &entries[j]
+		if foldersFirst && a.Dir != b.Dir {
+			return a.Dir
+		}
+		return less(a, b)
+	})
+}
+
+// lessFold compares case insensitive, the case only decides between names
+// that are otherwise equal
+func lessFold(a, b string) bool {
+	if la, lb := strings.ToLower(a), strings.ToLower(b); la != lb {
+		return la < lb
+	}
+	return a < b
+}
+
+// lessNatural is lessFold with numbers compared by their value, "file2"
+// comes before "file10"
+func lessNatural(a, b string) bool {
+	la, lb := strings.ToLower(a), strings.ToLower(b)
+	for la != "" && lb != "" {
+		na, nb := digits(la), digits(lb)
+		if na > 0 && nb > 0 {
+			da := strings.TrimLeft(la[:na], "0")
+			db := strings.TrimLeft(lb[:nb], "0")
+			if len(da) != len(db) {
+				return len(da) < len(db)
+			}
+			if da != db {
+				return da < db
+			}
+			la, lb = la[na:], lb[nb:]
+			continue
+		}
+		if la[0] != lb[0] {
+			return la[0] < lb[0]
+		}
+		la, lb = la[1:], lb[1:]
+	}
+	if la != lb {
+		return la == ""
+	}
+	return lessFold(a, b)
+}
+
+func digits(s string) (n int) {
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return
+}
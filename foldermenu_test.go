@@ -0,0 +1,227 @@
+package main
+
+import (
+	"io/fs"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"GoShell/config"
+)
+
+// testFolders reads a MapFS and records the folders that were read
+type testFolders struct {
+	fsys fstest.MapFS
+	read []string
+}
+
+func (f *testFolders) readDir(path string) ([]fs.DirEntry, error) {
+	f.read = append(f.read, path)
+	return fs.ReadDir(f.fsys, path)
+}
+
+func newTestFolders(files ...string) *testFolders {
+	fsys := fstest.MapFS{}
+	for _, file := range files {
+		if file[len(file)-1] == '/' {
+			fsys[file[:len(file)-1]] = &fstest.MapFile{Mode: fs.ModeDir}
+		} else {
+			fsys[file] = &fstest.MapFile{}
+		}
+	}
+	return &testFolders{fsys: fsys}
+}
+
+func entryNames(entries []folderEntry) (names []string) {
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	return
+}
+
+func TestListFolderMerge(t *testing.T) {
+	f := newTestFolders(
+		"user/Tools/a.lnk",
+		"user/Word.lnk",
+		"user/desktop.ini",
+		"common/tools/b.lnk",
+		"common/word.lnk",
+		"common/Excel.lnk",
+		"common/Setup.exe",
+	)
+	o := &config.FolderOptions{Exclude: []string{"setup*"}}
+	entries, err := listFolder(o, []string{"user", "common"}, 1, f.readDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := entryNames(entries), []string{"Tools", "Excel.lnk", "Word.lnk"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// the folders with the same name are one submenu, of the files the first is shown
+	if got, want := entries[0].Paths, []string{"user/Tools", "common/tools"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tools: got %q, want %q", got, want)
+	}
+	if got, want := entries[2].Paths, []string{"user/Word.lnk"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Word: got %q, want %q", got, want)
+	}
+
+	// a missing folder is reported, the others are still listed
+	entries, err = listFolder(o, []string{"missing", "user"}, 1, f.readDir)
+	if err == nil || len(entries) != 2 {
+		t.Errorf("got %q, %v", entryNames(entries), err)
+	}
+}
+
+func TestListFolderOptions(t *testing.T) {
+	f := newTestFolders("menu/Sub/x.lnk", "menu/file10.txt", "menu/File2.txt", "menu/a.lnk")
+	foldersLast := false
+	for _, tt := range []struct {
+		name string
+		o    config.FolderOptions
+		want []string
+	}{
+		{"default", config.FolderOptions{}, []string{"Sub", "a.lnk", "file10.txt", "File2.txt"}},
+		{"natural", config.FolderOptions{Sort: "natural"}, []string{"Sub", "a.lnk", "File2.txt", "file10.txt"}},
+		{"folders not first", config.FolderOptions{FoldersFirst: &foldersLast}, []string{"a.lnk", "file10.txt", "File2.txt", "Sub"}},
+		{"include", config.FolderOptions{Include: []string{"*.lnk"}}, []string{"Sub", "a.lnk"}},
+		{"max depth", config.FolderOptions{MaxDepth: 1}, []string{"a.lnk", "file10.txt", "File2.txt"}},
+	} {
+		entries, err := listFolder(&tt.o, []string{"menu"}, 1, f.readDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := entryNames(entries); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestListFolderFlatten(t *testing.T) {
+	f := newTestFolders(
+		"menu/Notepad++/Notepad++.lnk",
+		"menu/Tools/a.lnk",
+		"menu/Tools/b.lnk",
+		"menu/Deep/Inner/x.lnk",
+		"menu/Empty/",
+		"menu/readme.txt",
+	)
+	o := &config.FolderOptions{Flatten: true}
+	entries, err := listFolder(o, []string{"menu"}, 1, f.readDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := entryNames(entries), []string{"Empty", "Inner", "Tools", "Notepad++.lnk", "readme.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, e := range entries {
+		var want []string
+		switch e.Name {
+		case "Inner":
+			want = []string{"menu/Deep"}
+		case "Notepad++.lnk":
+			want = []string{"menu/Notepad++"}
+		}
+		if !reflect.DeepEqual(e.Folded, want) {
+			t.Errorf("%s: folded %q, want %q", e.Name, e.Folded, want)
+		}
+	}
+
+	// only the folders of the menu are probed, not the ones below them
+	sort.Strings(f.read)
+	if want := []string{"menu", "menu/Deep", "menu/Empty", "menu/Notepad++", "menu/Tools"}; !reflect.DeepEqual(f.read, want) {
+		t.Errorf("read %q, want %q", f.read, want)
+	}
+
+	// the folded folder is flattened when its menu is filled
+	entries, _ = listFolder(o, entries[1].Paths, 2, f.readDir)
+	if got, want := entryNames(entries), []string{"x.lnk"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Inner: got %q, want %q", got, want)
+	}
+}
+
+func TestListFolderWithoutFlatten(t *testing.T) {
+	f := newTestFolders("menu/Notepad++/Notepad++.lnk", "menu/Deep/Inner/x.lnk")
+	entries, err := listFolder(&config.FolderOptions{}, []string{"menu"}, 1, f.readDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := entryNames(entries), []string{"Deep", "Notepad++"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if want := []string{"menu"}; !reflect.DeepEqual(f.read, want) {
+		t.Errorf("read %q, want %q", f.read, want)
+	}
+}
+
+// testModTimes are the modification times of the folders, a missing one is
+// the zero time like for a folder that can't be read
+type testModTimes map[string]time.Time
+
+func (m testModTimes) modTime(path string) time.Time {
+	return m[path]
+}
+
+func TestFolderCache(t *testing.T) {
+	f := newTestFolders("menu/Deep/Inner/x.lnk", "menu/Sub/y.lnk", "menu/Sub/z.lnk", "menu/a.lnk")
+	times := testModTimes{"menu": time.Unix(1, 0), "menu/Deep": time.Unix(2, 0)}
+	c := &folderCache{paths: []string{"menu"}, options: &config.FolderOptions{Flatten: true}, depth: 1}
+	if !c.changed(times.modTime) {
+		t.Error("not changed before it was read")
+	}
+	entries, err := c.list(f.readDir, times.modTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the folded folder is read with the menu
+	if want := []string{"menu", "menu/Deep"}; !reflect.DeepEqual(c.read, want) {
+		t.Errorf("read %q, want %q", c.read, want)
+	}
+	if c.changed(times.modTime) {
+		t.Error("changed after it was read")
+	}
+	times["menu/Deep"] = time.Unix(3, 0)
+	if !c.changed(times.modTime) {
+		t.Error("not changed after a folded folder changed")
+	}
+
+	f.read = nil
+	if _, err := c.list(f.readDir, times.modTime); err != nil || len(f.read) == 0 {
+		t.Fatalf("read again %q, %v", f.read, err)
+	}
+	if got, want := entryNames(entries), []string{"Inner", "Sub", "a.lnk"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	sub := &folderCache{paths: entries[1].Paths, options: c.options, depth: 2}
+	if _, err := sub.list(f.readDir, times.modTime); err != nil {
+		t.Fatal(err)
+	}
+	c.children = []*folderCache{sub}
+
+	for _, tt := range []struct {
+		name          string
+		change        func(changed *changedFolders)
+		menu, submenu bool
+	}{
+		{"other folder", func(changed *changedFolders) { changed.add("other") }, false, false},
+		{"submenu", func(changed *changedFolders) { changed.add("menu/Sub") }, false, true},
+		{"folded folder", func(changed *changedFolders) { changed.add("menu/Deep") }, true, false},
+		{"both", func(changed *changedFolders) { changed.add("menu"); changed.add("menu/Sub") }, true, true},
+	} {
+		// filled again, nothing changed
+		c.list(f.readDir, times.modTime)
+		sub.list(f.readDir, times.modTime)
+		c.children = []*folderCache{sub}
+
+		var changed changedFolders
+		tt.change(&changed)
+		c.invalidate(&changed)
+		if got := c.changed(times.modTime); got != tt.menu {
+			t.Errorf("%s: menu changed %v, want %v", tt.name, got, tt.menu)
+		}
+		if got := sub.changed(times.modTime); got != tt.submenu {
+			t.Errorf("%s: submenu changed %v, want %v", tt.name, got, tt.submenu)
+		}
+	}
+}
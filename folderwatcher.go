@@ -0,0 +1,139 @@
+//go:build windows
+
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// watchFolders calls onChange with the changed folders below the roots until
+// stop is called, the changes of folderReloadDelay are passed at once
+// https://learn.microsoft.com/en-us/windows/win32/api/winbase/nf-winbase-readdirectorychangesw
+func watchFolders(roots []string, onChange func(changed changedFolders)) (stop func()) {
+	done, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		log.Println(err)
+		return func() {}
+	}
+
+	type watch struct {
+		root   string
+		dir    windows.Handle
+		ov     windows.Overlapped
+		buffer []byte
+	}
+	const mask = windows.FILE_NOTIFY_CHANGE_FILE_NAME | windows.FILE_NOTIFY_CHANGE_DIR_NAME | windows.FILE_NOTIFY_CHANGE_LAST_WRITE
+	read := func(w *watch) error {
+		return windows.ReadDirectoryChanges(w.dir, &w.buffer[0], uint32(len(w.buffer)), true, mask, nil, &w.ov, 0)
+	}
+
+	if len(roots) > maxWatchRoots {
+		roots = roots[:maxWatchRoots]
+	}
+	handles := []windows.Handle{done} // done and the HEvent of watches[i] at i+1
+	var watches []*watch
+	for _, root := range roots {
+		name, err := windows.UTF16PtrFromString(root)
+		if err != nil {
+			continue
+		}
+		dir, err := windows.CreateFile(name, windows.FILE_LIST_DIRECTORY,
+			windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+			nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OVERLAPPED, 0)
+		if err != nil {
+			log.Println(root, err)
+			continue
+		}
+		w := &watch{root: root, dir: dir, buffer: make([]byte, 64*1024)}
+		w.ov.HEvent, err = windows.CreateEvent(nil, 0, 0, nil)
+		if err == nil {
+			err = read(w)
+		}
+		if err != nil {
+			log.Println(root, err)
+			windows.CloseHandle(w.ov.HEvent)
+			windows.CloseHandle(dir)
+			continue
+		}
+		watches = append(watches, w)
+		handles = append(handles, w.ov.HEvent)
+	}
+
+	changes := &folderChanges{delay: folderReloadDelay, onChange: onChange}
+	// drop stops watching the root of watches[i], e.g. when it was deleted
+	drop := func(i int) {
+		w := watches[i]
+		windows.CancelIoEx(w.dir, &w.ov)
+		var n uint32
+		windows.GetOverlappedResult(w.dir, &w.ov, &n, true)
+		windows.CloseHandle(w.ov.HEvent)
+		windows.CloseHandle(w.dir)
+		watches = append(watches[:i], watches[i+1:]...)
+		handles = append(handles[:i+1], handles[i+2:]...)
+	}
+
+	go func() {
+		defer func() {
+			for len(watches) != 0 {
+				drop(0)
+			}
+			windows.CloseHandle(done)
+		}()
+
+		for {
+			event, err := windows.WaitForMultipleObjects(handles, false, windows.INFINITE)
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			i := int(event - windows.WAIT_OBJECT_0)
+			if i == 0 {
+				changes.stop()
+				return
+			}
+
+			w := watches[i-1]
+			var n uint32
+			if err := windows.GetOverlappedResult(w.dir, &w.ov, &n, false); err != nil {
+				log.Println("not watched anymore:", w.root, err)
+				drop(i - 1)
+				// its menus are read again when they open
+				changes.record(func(changed *changedFolders) { changed.addTree(w.root) })
+				continue
+			}
+			changes.record(func(changed *changedFolders) {
+				if n == 0 {
+					// the buffer was too small for the changes
+					changed.addTree(w.root)
+				}
+				for offset := uint32(0); offset < n; {
+					info := (*windows.FileNotifyInformation)(unsafe.Pointer(&w.buffer[offset]))
+					path := filepath.Join(w.root, windows.UTF16ToString(unsafe.Slice(&info.FileName, info.FileNameLength/2)))
+					// a folder is modified with every change in it, that is
+					// reported on its own
+					if fi, err := os.Stat(path); info.Action != windows.FILE_ACTION_MODIFIED || err != nil || !fi.IsDir() {
+						changed.add(filepath.Dir(path))
+					}
+					if info.NextEntryOffset == 0 {
+						break
+					}
+					offset += info.NextEntryOffset
+				}
+			})
+
+			if err := read(w); err != nil {
+				log.Println("not watched anymore:", w.root, err)
+				drop(i - 1)
+			}
+		}
+	}()
+
+	return func() {
+		windows.SetEvent(done)
+	}
+}
@@ -8,7 +8,7 @@ require (
 	github.com/leaanthony/winc v0.0.0-20220323084916-ea5df694ec1f
 	github.com/parsiya/golnk v0.0.0-20221103095132-740a4c27c4ff
 	golang.org/x/sys v0.9.0
-	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -5,17 +5,8 @@ import (
 	"log"
 	"path/filepath"
 	"strconv"
-	"syscall"
 )
 
-func UTF16PtrFromString(s string) *uint16 {
-	ret, err := syscall.UTF16PtrFromString(s)
-	if err != nil {
-		log.Println(err)
-	}
-	return ret
-}
-
 func StringToInt(value string) int {
 	i, err := strconv.Atoi(value)
 	if err != nil {
@@ -1,37 +1,36 @@
+//go:build windows
+
 package main
 
 import (
-	"strings"
+	"log"
 
-	"github.com/leaanthony/winc"
+	"GoShell/config"
 	"github.com/leaanthony/winc/w32"
 )
 
-func SetupHotkeys(hWnd uintptr) (keyboardHook uintptr) {
-	for i, hk := range config.Hotkey {
-		keys := strings.ToUpper(hk.Buttons)
-		arr := strings.Split(keys, "+")
-		var hotkey string
-		var fsModifiers uint32
-		for _, v := range arr {
-			switch v {
-			case "WIN":
-				fsModifiers |= w32.MOD_WIN
-
-			case "ALT":
-				fsModifiers |= w32.MOD_ALT
+// ids of the hotkeys that are currently registered
+var registeredHotkeys []int
 
-			case "CTRL":
-			case "STRG":
-				fsModifiers |= w32.MOD_CONTROL
-
-			case "SHIFT":
-				fsModifiers |= w32.MOD_SHIFT
-			default:
-				hotkey = v
-			}
+func SetupHotkeys(hWnd uintptr) (keyboardHook uintptr) {
+	for i, hk := range cfg.Hotkey {
+		fsModifiers, vk, err := config.ParseHotkey(hk.Buttons)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if w32.RegisterHotKey(hWnd, i, fsModifiers, vk) {
+			registeredHotkeys = append(registeredHotkeys, i)
+		} else {
+			log.Printf("RegisterHotKey %q failed\n", hk.Buttons)
 		}
-		w32.RegisterHotKey(hWnd, i, fsModifiers, uint32(winc.String2key[hotkey]))
 	}
 	return
 }
+
+func UnregisterHotkeys(hWnd uintptr) {
+	for _, id := range registeredHotkeys {
+		w32.UnregisterHotKey(hWnd, id)
+	}
+	registeredHotkeys = nil
+}
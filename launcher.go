@@ -0,0 +1,412 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+
+	"GoShell/config"
+	"github.com/leaanthony/winc/w32"
+	"golang.org/x/sys/windows"
+)
+
+// shellLauncher starts the actions of the config
+type shellLauncher struct {
+	s *shell
+}
+
+func (l shellLauncher) Launch(launch config.Launch) error {
+	if launch.FocusExisting != nil {
+		focused, err := l.s.FocusExisting(launch.FocusExisting, launch.File)
+		if focused || err != nil {
+			return err
+		}
+	}
+
+	if launch.Kind == "command" {
+		return l.s.RunCommand(launch.File, launch.Args)
+	}
+	return processLauncher{}.Launch(launch)
+}
+
+// processLauncher only starts programs, for the places without a shell like
+// the startup
+type processLauncher struct{}
+
+func (p processLauncher) Launch(launch config.Launch) error {
+	_, err := p.Start(launch)
+	return err
+}
+
+// launchMu serializes the start of every process: the actions of the menus
+// and hotkeys, the startup items and the services. ShellExecute has no
+// parameter for the environment, so shellExecute changes the environment of
+// GoShell for the time of the call. A process started at the same time by
+// another goroutine would inherit these changes, and MergeEnv(os.Environ())
+// could read them half done.
+var launchMu sync.Mutex
+
+// Start is Launch with the id of the new process, it is 0 for shellExecute
+// because the shell doesn't tell which program it started
+func (processLauncher) Start(launch config.Launch) (pid uint32, err error) {
+	launchMu.Lock()
+	defer launchMu.Unlock()
+
+	switch launch.Kind {
+	case "shellExecute":
+		return 0, shellExecute(launch)
+	case "createProcess":
+		return createProcess(launch)
+	case "openProcess":
+		return openProcess(launch)
+	}
+	return 0, fmt.Errorf("%s is not possible here", launch.Kind)
+}
+
+// FocusExisting activates a window of the taskbar that matches f, it
+// returns false if there is none
+func (s *shell) FocusExisting(f *config.FocusExisting, file string) (bool, error) {
+	match, err := focusMatcher(f, file)
+	if err != nil || s.TaskbarWindow == nil {
+		return false, err
+	}
+
+	var matches []uintptr
+	for _, btn := range s.TaskbarWindow.tl.PushButtonList {
+		w := windowInfo{
+			hWnd:  btn.hWnd,
+			exe:   WindowExe(btn.hWnd),
+			class: w32.GetClassName(btn.hWnd),
+			title: WindowTitle(btn.hWnd),
+		}
+		if match(w) {
+			matches = append(matches, w.hWnd)
+		}
+	}
+	if len(matches) == 0 {
+		return false, nil
+	}
+	ActivateWindow(nextWindow(matches, uintptr(windows.GetForegroundWindow()), f.Cycle))
+	return true, nil
+}
+
+// OpenFile opens a file with the action of its FileHandler, errors are
+// logged and shown
+func (s *shell) OpenFile(file string) bool {
+	a := config.FileAction(cfg.FileHandlers, file)
+	if err := a.Run(s.launcher); err != nil {
+		log.Println(err)
+		w32.MessageBox(0, err.Error(), "GoShell", w32.MB_ICONERROR)
+		return false
+	}
+	return true
+}
+
+// OpenMenuFile opens a file of a folder menu: a shortcut starts its target,
+// other files open with their FileHandler
+func (s *shell) OpenMenuFile(file string) bool {
+	if !strings.EqualFold(filepath.Ext(file), ".lnk") {
+		return s.OpenFile(file)
+	}
+	sc, err := ReadShortcut(file, ExpandEnvironment)
+	if err != nil {
+		log.Println(err)
+	}
+	a := sc.Action()
+	if err := a.Run(s.launcher); err != nil {
+		log.Println(err)
+		w32.MessageBox(0, err.Error(), "GoShell", w32.MB_ICONERROR)
+		return false
+	}
+	return true
+}
+
+// Run runs an action of the config, errors are logged and shown.
+// name is the name for the recent menus, without one it is the file name.
+func (s *shell) Run(name string, a *config.Action) {
+	if err := a.Run(s.launcher); err != nil {
+		log.Println(err)
+		w32.MessageBox(0, err.Error(), "GoShell", w32.MB_ICONERROR)
+		return
+	}
+	s.AddRecent(name, a)
+}
+
+// https://learn.microsoft.com/en-us/windows/win32/api/shellapi/nf-shellapi-shellexecutew
+func shellExecute(l config.Launch) error {
+	lpFile, err := syscall.UTF16PtrFromString(l.File)
+	if err != nil {
+		return err
+	}
+	var lpParameters, lpDirectory, lpOperation *uint16
+	if params := l.Parameters(); params != "" {
+		lpParameters, err = syscall.UTF16PtrFromString(params)
+		if err != nil {
+			return err
+		}
+	}
+	if l.Workdir != "" {
+		lpDirectory, err = syscall.UTF16PtrFromString(l.Workdir)
+		if err != nil {
+			return err
+		}
+	}
+	if l.Verb != "" {
+		lpOperation, err = syscall.UTF16PtrFromString(strings.ToLower(l.Verb))
+		if err != nil {
+			return err
+		}
+	}
+
+	// ShellExecute has no parameter for the environment, the new process
+	// inherits it from GoShell, launchMu keeps the other starts out meanwhile
+	defer setEnv(l.Env)()
+
+	return windows.ShellExecute(
+		0,
+		lpOperation,
+		lpFile,
+		lpParameters,
+		lpDirectory,
+		showCmd(l, windows.SW_SHOWDEFAULT),
+	)
+}
+
+// https://docs.microsoft.com/de-de/windows/win32/procthread/creating-processes
+// https://docs.microsoft.com/en-us/windows/win32/procthread/process-creation-flags
+func createProcess(l config.Launch) (uint32, error) {
+	if l.NeedsShell() {
+		return 0, shellExecute(l)
+	}
+
+	lpApplicationName, err := syscall.UTF16PtrFromString(l.File)
+	if err != nil {
+		return 0, err
+	}
+	// the program reads its arguments from lpCommandLine and expects its own
+	// name as the first one
+	commandLine := config.QuoteArg(l.File)
+	if params := l.Parameters(); params != "" {
+		commandLine += " " + params
+	}
+	lpCommandLine, err := syscall.UTF16PtrFromString(commandLine)
+	if err != nil {
+		return 0, err
+	}
+	var lpCurrentDirectory *uint16
+	if l.Workdir != "" {
+		lpCurrentDirectory, err = syscall.UTF16PtrFromString(l.Workdir)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// https://learn.microsoft.com/en-us/windows/win32/api/processthreadsapi/ns-processthreadsapi-startupinfow
+	var startupInfo syscall.StartupInfo
+	startupInfo.Cb = uint32(unsafe.Sizeof(startupInfo))
+	startupInfo.Flags = windows.STARTF_USESHOWWINDOW
+	startupInfo.ShowWindow = uint16(showCmd(l, windows.SW_SHOWNORMAL))
+	var processInformation syscall.ProcessInformation
+
+	var creationFlags uint32
+	if l.Hidden || strings.EqualFold(l.Show, "hidden") {
+		creationFlags = windows.NORMAL_PRIORITY_CLASS | windows.CREATE_NO_WINDOW | windows.CREATE_NEW_PROCESS_GROUP
+	} else {
+		creationFlags = windows.NORMAL_PRIORITY_CLASS | windows.CREATE_NEW_CONSOLE | windows.CREATE_NEW_PROCESS_GROUP
+	}
+	var lpEnvironment *uint16
+	if l.Env != nil {
+		lpEnvironment = envBlock(config.MergeEnv(os.Environ(), l.Env))
+		creationFlags |= windows.CREATE_UNICODE_ENVIRONMENT
+	}
+
+	err = syscall.CreateProcess(
+		lpApplicationName,   // No module name (use command line)
+		lpCommandLine,       // Command line
+		nil,                 // Process handle not inheritable
+		nil,                 // Thread handle not inheritable
+		false,               // Set handle inheritance to FALSE
+		creationFlags,       // creation flags
+		lpEnvironment,       // nil uses the parent's environment block
+		lpCurrentDirectory,  // nil uses the parent's starting directory
+		&startupInfo,        // Pointer to STARTUPINFO structure
+		&processInformation) // Pointer to PROCESS_INFORMATION structure
+	if err != nil {
+		return 0, err
+	}
+
+	// WaitForSingleObject(processInfo.hProcess, INFINITE);
+	syscall.CloseHandle(processInformation.Thread)
+	return processInformation.ProcessId, syscall.CloseHandle(processInformation.Process)
+}
+
+// Start starts the specified command but does not wait for it to complete.
+func openProcess(l config.Launch) (uint32, error) {
+	if l.NeedsShell() {
+		return 0, shellExecute(l)
+	}
+	show := strings.ToLower(l.Show)
+	if show == "minimized" || show == "maximized" {
+		// os/exec can only hide the window
+		path, err := exec.LookPath(l.File)
+		if err != nil {
+			return 0, err
+		}
+		l.File = path
+		return createProcess(l)
+	}
+
+	cmd := newCommand(l)
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	return uint32(cmd.Process.Pid), cmd.Process.Release()
+}
+
+// newCommand returns the os/exec command for openProcess and the services
+func newCommand(l config.Launch) *exec.Cmd {
+	cmd := exec.Command(l.File)
+	cmd.Dir = l.Workdir
+	if l.Env != nil {
+		cmd.Env = config.MergeEnv(os.Environ(), l.Env)
+	}
+	// CmdLine replaces the quoting of os/exec, so all three ways quote alike
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: l.Hidden || strings.EqualFold(l.Show, "hidden"), CmdLine: config.QuoteArg(l.File)}
+	if params := l.Parameters(); params != "" {
+		cmd.SysProcAttr.CmdLine += " " + params
+	}
+	return cmd
+}
+
+// processStarter starts the programs of the services
+type processStarter struct{}
+
+func (processStarter) Start(l config.Launch) (ServiceProcess, error) {
+	launchMu.Lock()
+	cmd := newCommand(l)
+	err := cmd.Start()
+	launchMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	p := &process{cmd: cmd, done: make(chan struct{})}
+	go func() {
+		p.err = cmd.Wait()
+		close(p.done)
+	}()
+	return p, nil
+}
+
+type process struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+	err  error
+}
+
+func (p *process) Wait() (int, error) {
+	<-p.done
+	if _, ok := p.err.(*exec.ExitError); ok {
+		return p.cmd.ProcessState.ExitCode(), nil
+	}
+	return 0, p.err
+}
+
+// Stop closes the windows of the program like the X button, programs
+// without windows and the ones that don't react in time are killed
+func (p *process) Stop(timeout time.Duration) error {
+	closeWindows(uint32(p.cmd.Process.Pid))
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(timeout):
+	}
+	if err := p.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	return nil
+}
+
+// closeWindows sends WM_CLOSE to the top-level windows of the process, also
+// to the hidden ones like the window of an AutoHotkey script
+func closeWindows(pid uint32) {
+	cb := syscall.NewCallback(func(h syscall.Handle, _ uintptr) uintptr {
+		var p uint32
+		if _, err := windows.GetWindowThreadProcessId(windows.HWND(h), &p); err == nil && p == pid {
+			w32.PostMessage(w32.HWND(h), w32.WM_CLOSE, 0, 0)
+		}
+		return 1
+	})
+	w32.EnumWindows(cb, 0)
+}
+
+// showCmd returns the SW_ value for show, def if nothing is set
+// https://learn.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-showwindow
+func showCmd(l config.Launch, def int32) int32 {
+	if l.Hidden {
+		return windows.SW_HIDE
+	}
+	switch strings.ToLower(l.Show) {
+	case "normal":
+		return windows.SW_SHOWNORMAL
+	case "minimized":
+		return windows.SW_SHOWMINNOACTIVE // without taking the focus
+	case "maximized":
+		return windows.SW_SHOWMAXIMIZED
+	case "hidden":
+		return windows.SW_HIDE
+	}
+	return def
+}
+
+// envBlock returns the environment block for CreateProcess, every
+// "key=value" ends with a 0 and the block with another one
+func envBlock(environ []string) *uint16 {
+	var block []uint16
+	for _, kv := range environ {
+		block = append(block, utf16.Encode([]rune(kv))...)
+		block = append(block, 0)
+	}
+	block = append(block, 0)
+	if len(environ) == 0 {
+		block = append(block, 0)
+	}
+	return &block[0]
+}
+
+// setEnv changes the environment of GoShell and returns the function that
+// restores it, only with launchMu held
+func setEnv(env map[string]string) (restore func()) {
+	old := make(map[string]*string, len(env))
+	for k, v := range env {
+		if prev, ok := os.LookupEnv(k); ok {
+			old[k] = &prev
+		} else {
+			old[k] = nil
+		}
+		if v == "" {
+			os.Unsetenv(k)
+		} else {
+			os.Setenv(k, v)
+		}
+	}
+	return func() {
+		for k, v := range old {
+			if v == nil {
+				os.Unsetenv(k)
+			} else {
+				os.Setenv(k, *v)
+			}
+		}
+	}
+}
@@ -1,11 +1,15 @@
+//go:build windows
+
 package main
 
 import (
+	"fmt"
 	"log"
 	"strings"
 	"syscall"
 	"unsafe"
 
+	"GoShell/config"
 	"github.com/leaanthony/winc"
 	"github.com/leaanthony/winc/w32"
 	"golang.org/x/sys/windows/registry"
@@ -14,6 +18,18 @@ import (
 type shell struct {
 	mainWindow    *DesktopForm
 	TaskbarWindow *TaskbarForm
+	stopWatcher   func()
+	taskbarHidden bool // !toggleTaskbar
+	launcher      config.Launcher
+	services      *Supervisor
+	// the services of the last config for syncServices, only the UI thread
+	// sends
+	serviceUpdates chan []config.Service
+	recent         *RecentList
+	recentMenus    []recentMenu
+	// the submenus of the path entries
+	folderMenus       []*folderMenu
+	stopFolderWatcher func()
 }
 
 type MonitorRect struct {
@@ -28,7 +44,7 @@ var (
 )
 
 func main() {
-	if config.Desktop.Contextmenu.DarkMode {
+	if cfg.Desktop.Contextmenu.DarkMode {
 		w32.SetPreferredAppMode(w32.AllowDark)
 	}
 
@@ -79,7 +95,10 @@ func main() {
 	}
 
 	s := new(shell)
+	s.launcher = shellLauncher{s}
+	s.LoadRecent()
 	s.mainWindow = NewDesktopForm(nil)
+	s.mainWindow.shell = s
 	s.mainWindow.SetSize(SM_CXVIRTUALSCREEN, SM_CYVIRTUALSCREEN)
 
 	s.mainWindow.OnPaint().Bind(func(arg *winc.Event) {
@@ -126,32 +145,28 @@ func main() {
 
 	s.mainWindow.SetContextMenu(s.ContextMenu())
 	s.mainWindow.SetMiddleMenuFunc(s.MiddleMenu)
+	s.mainWindow.EnableDragAcceptFiles(true)
+	s.mainWindow.OnDropFiles().Bind(func(arg *winc.Event) {
+		for _, file := range arg.Data.(*winc.DropFilesEventData).Files {
+			s.OpenFile(file)
+		}
+	})
 
 	// Taskleiste
 	tl := new(taskList)
-	if config.Taskbar.IconPosition == "center" {
-		tl.centered = true
-	}
 	s.TaskbarWindow = NewTaskbarForm(s.mainWindow, tl)
-	s.TaskbarWindow.SetSize(SM_CXSCREEN, config.Taskbar.Height)
-	if config.Taskbar.Position == "top" {
-		SetPos(s.TaskbarWindow.Handle(), 0, 0)
-		SetWorkspace(winc.NewRect(0, config.Taskbar.Height, int(PrimaryMonitor.Rect.Right), int(PrimaryMonitor.Rect.Bottom)))
-	} else {
-		SetPos(s.TaskbarWindow.Handle(), 0, int(PrimaryMonitor.Rect.Bottom)-config.Taskbar.Height)
-		SetWorkspace(winc.NewRect(0, 0, int(PrimaryMonitor.Rect.Right), int(PrimaryMonitor.Rect.Bottom)-config.Taskbar.Height))
-	}
+	s.LayoutTaskbar()
 
 	s.TaskbarWindow.OnPaint().Bind(func(arg *winc.Event) {
 		if p, ok := arg.Data.(*winc.PaintEventData); ok {
 			p.Canvas.DrawFillRect(
-				winc.NewRect(0, 0, SM_CXSCREEN, config.Taskbar.Height),
+				winc.NewRect(0, 0, SM_CXSCREEN, cfg.Taskbar.Height),
 				winc.NewPen(w32.PS_GEOMETRIC, 0, winc.NewSolidColorBrush(winc.RGB(0, 0, 0))),
-				winc.NewSolidColorBrush(winc.RGB(byte(config.Taskbar.Bgcolor.R), byte(config.Taskbar.Bgcolor.G), byte(config.Taskbar.Bgcolor.B))),
+				winc.NewSolidColorBrush(rgb(cfg.Taskbar.Bgcolor)),
 			)
 		}
 	})
-	s.TaskbarWindow.SetContextMenu(s.TaskbarWindow.ContextMenu())
+	s.TaskbarWindow.SetContextMenu(s.TaskbarMenu())
 	list := GetProcesses()
 	for i := 0; i < len(list); i++ {
 		tl.Add(s.TaskbarWindow, list[i])
@@ -165,8 +180,113 @@ func main() {
 
 	tl.Refresh(s.TaskbarWindow, false)
 
+	s.WatchConfig()
+	s.WatchFolders()
+
+	s.services = NewSupervisor(processStarter{})
+	s.services.onGiveUp = func(svc config.Service, err error) {
+		s.mainWindow.Invoke(func() {
+			w32.MessageBox(0, fmt.Sprintf("%s (%s) exits again and again and is not restarted anymore: %v", svc.Name, svc.Program, err), "GoShell services", w32.MB_ICONWARNING)
+		})
+	}
+	s.serviceUpdates = make(chan []config.Service, 1)
+	go s.runServiceUpdates()
+	s.syncServices(cfg.Services)
+
 	// s.TaskbarWindow.GetTaskbarState()
 	winc.RunMainLoop()
+	close(s.serviceUpdates)
+	s.services.Stop()
+}
+
+// syncServices hands the services of the config to runServiceUpdates, a
+// config that wasn't applied yet is replaced by the newer one
+func (s *shell) syncServices(services []config.Service) {
+	select {
+	case <-s.serviceUpdates:
+	default:
+	}
+	s.serviceUpdates <- services
+}
+
+// runServiceUpdates applies the services off the UI thread, stopping the
+// changed ones can take serviceStopTimeout. Problems are shown by the UI
+// thread.
+func (s *shell) runServiceUpdates() {
+	for services := range s.serviceUpdates {
+		if err := s.services.Sync(services); err != nil {
+			s.mainWindow.Invoke(func() {
+				w32.MessageBox(0, err.Error(), "GoShell services", w32.MB_ICONWARNING)
+			})
+		}
+	}
+}
+
+// WatchConfig reloads the config when one of its files changes
+func (s *shell) WatchConfig() {
+	if s.stopWatcher != nil {
+		s.stopWatcher()
+	}
+	s.stopWatcher = watchConfig(cfg.Files(), func() {
+		s.mainWindow.Invoke(s.Reload)
+	})
+}
+
+// LayoutTaskbar applies the size and position from the config to the taskbar
+func (s *shell) LayoutTaskbar() {
+	if s.taskbarHidden {
+		s.TaskbarWindow.Hide()
+		SetWorkspace(winc.NewRect(0, 0, int(PrimaryMonitor.Rect.Right), int(PrimaryMonitor.Rect.Bottom)))
+		return
+	}
+	s.TaskbarWindow.Show()
+
+	s.TaskbarWindow.tl.centered = cfg.Taskbar.IconPosition == "center"
+	s.TaskbarWindow.SetSize(SM_CXSCREEN, cfg.Taskbar.Height)
+	s.TaskbarWindow.SetOpacity(cfg.Taskbar.Bgcolor.A)
+	if cfg.Taskbar.Position == "top" {
+		SetPos(s.TaskbarWindow.Handle(), 0, 0)
+		SetWorkspace(winc.NewRect(0, cfg.Taskbar.Height, int(PrimaryMonitor.Rect.Right), int(PrimaryMonitor.Rect.Bottom)))
+	} else {
+		SetPos(s.TaskbarWindow.Handle(), 0, int(PrimaryMonitor.Rect.Bottom)-cfg.Taskbar.Height)
+		SetWorkspace(winc.NewRect(0, 0, int(PrimaryMonitor.Rect.Right), int(PrimaryMonitor.Rect.Bottom)-cfg.Taskbar.Height))
+	}
+}
+
+// Reload reads the config again and applies it to the running shell,
+// if the file can't be parsed the current config is kept.
+// It has to be called from the UI thread.
+func (s *shell) Reload() {
+	c, err := config.ReadConfig(configPath)
+	if err != nil {
+		log.Println(err)
+		w32.MessageBox(0, err.Error(), "Reload config.yaml", w32.MB_ICONERROR)
+		return
+	}
+	log.Println("reload", configPath)
+	offerMigration(c)
+
+	UnregisterHotkeys(s.mainWindow.Handle())
+	cfg = c
+	s.WatchConfig() // the includes may have changed
+
+	if cfg.Desktop.Contextmenu.DarkMode {
+		w32.SetPreferredAppMode(w32.AllowDark)
+	} else {
+		w32.SetPreferredAppMode(w32.Default)
+	}
+	s.Refresh()
+	SetupHotkeys(s.mainWindow.Handle())
+	s.syncServices(cfg.Services)
+
+	s.LayoutTaskbar()
+	w32.SetWindowPos(s.TaskbarWindow.Handle(), w32.HWND_TOPMOST, 0, 0, 0, 0, w32.SWP_NOACTIVATE|w32.SWP_NOSIZE|w32.SWP_NOMOVE)
+	s.TaskbarWindow.tl.Refresh(s.TaskbarWindow, false)
+	s.TaskbarWindow.Invalidate(true)
+	for _, btn := range s.TaskbarWindow.tl.PushButtonList {
+		btn.SetSize(cfg.Taskbar.Button.Size.Width, cfg.Taskbar.Button.Size.Height)
+		btn.Invalidate(true)
+	}
 }
 
 // func MakeSticky(hWnd w32.HWND) {
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// GoShell needs the Windows API, on other systems only the config package
+// can be built and tested
+func main() {
+	fmt.Fprintln(os.Stderr, "GoShell runs only on Windows, use cmd/checkconfig to check a config.yaml")
+	os.Exit(1)
+}
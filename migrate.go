@@ -0,0 +1,35 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"GoShell/config"
+
+	"github.com/leaanthony/winc/w32"
+)
+
+// files the user didn't want to migrate, so they are not asked again on every reload
+var declinedMigrations = map[string]bool{}
+
+// offerMigration asks to write the migrated version of the files that have
+// an older version back to disk, the original is kept as .bak
+func offerMigration(c *config.Config) {
+	for _, file := range c.Migrated() {
+		if declinedMigrations[strings.ToLower(file)] {
+			continue
+		}
+		text := fmt.Sprintf("%s was written for an older GoShell and was updated while loading.\n\nSave the updated version? The original is kept as %s.bak", file, file)
+		if w32.MessageBox(0, text, "Migrate config", w32.MB_YESNO|w32.MB_ICONQUESTION) != w32.IDYES {
+			declinedMigrations[strings.ToLower(file)] = true
+			continue
+		}
+		if err := config.MigrateConfigFile(file); err != nil {
+			log.Println(err)
+			w32.MessageBox(0, err.Error(), "Migrate config", w32.MB_ICONERROR)
+		}
+	}
+}
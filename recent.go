@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"GoShell/config"
+	"gopkg.in/yaml.v3"
+)
+
+// RecentItem is a launched action or an opened file of a folder menu in the
+// state file
+type RecentItem struct {
+	Name          string `yaml:"name"`
+	config.Action `yaml:",inline"`
+	// the file of a folder menu, it is opened like in the folder menu and
+	// not with the action
+	File   string    `yaml:"file,omitempty"`
+	Pinned bool      `yaml:"pinned,omitempty"`
+	Time   time.Time `yaml:"time"`
+}
+
+// RecentList is the history of the recent menus, it is kept in the profile
+// of the user (see RecentFilePath) and not in the config itself
+type RecentList struct {
+	Items []RecentItem `yaml:"items"`
+	file  string
+}
+
+// RecentFilePath is %APPDATA%\GoShell\recent.yaml, the config itself can be
+// shared or read only. It is "" without APPDATA.
+func RecentFilePath() string {
+	dir := os.Getenv("APPDATA")
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "GoShell", "recent.yaml")
+}
+
+// LoadRecentList reads the state file, a missing file is an empty list.
+// Without file the list is only kept in memory.
+func LoadRecentList(file string) (*RecentList, error) {
+	r := &RecentList{file: file}
+	if file == "" {
+		return r, nil
+	}
+	content, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	} else if err != nil {
+		return r, err
+	}
+	if err := yaml.Unmarshal(content, r); err != nil {
+		return &RecentList{file: file}, err
+	}
+	return r, nil
+}
+
+// Save writes the state file
+func (r *RecentList) Save() error {
+	if r.file == "" {
+		return nil
+	}
+	content, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+	content = append([]byte("# written by GoShell, the items of the recent menus\n"), content...)
+	if err := os.MkdirAll(filepath.Dir(r.file), 0o755); err != nil {
+		return err
+	}
+	return config.WriteFileAtomic(r.file, content)
+}
+
+// Add moves the item to the top, or adds it there. Only the newest size
+// items that are not pinned are kept.
+func (r *RecentList) Add(item RecentItem, now time.Time, size int) {
+	key := recentKey(&item)
+	item.Time, item.Pinned = now, false
+	for i := range r.Items {
+		if recentKey(&r.Items[i]) == key {
+			item.Pinned = r.Items[i].Pinned
+			r.Items = append(r.Items[:i], r.Items[i+1:]...)
+			break
+		}
+	}
+	r.Items = append([]RecentItem{item}, r.Items...)
+
+	kept := r.Items[:0]
+	unpinned := 0
+	for _, it := range r.Items {
+		if !it.Pinned {
+			if unpinned >= size {
+				continue
+			}
+			unpinned++
+		}
+		kept = append(kept, it)
+	}
+	r.Items = kept
+}
+
+// SetPinned pins the item with the key of item to the top or unpins it
+func (r *RecentList) SetPinned(item *RecentItem, pinned bool) {
+	key := recentKey(item)
+	for i := range r.Items {
+		if recentKey(&r.Items[i]) == key {
+			r.Items[i].Pinned = pinned
+		}
+	}
+}
+
+// Clear removes every item that is not pinned
+func (r *RecentList) Clear() {
+	kept := r.Items[:0]
+	for _, it := range r.Items {
+		if it.Pinned {
+			kept = append(kept, it)
+		}
+	}
+	r.Items = kept
+}
+
+// Menu returns the pinned items and the newest size others, the newest first
+func (r *RecentList) Menu(size int) (pinned, recent []RecentItem) {
+	for _, it := range r.Items {
+		if it.Pinned {
+			pinned = append(pinned, it)
+		} else if len(recent) < size {
+			recent = append(recent, it)
+		}
+	}
+	sort.SliceStable(pinned, func(i, j int) bool { return strings.ToLower(pinned[i].Name) < strings.ToLower(pinned[j].Name) })
+	return
+}
+
+// recentKey is the same for items that launch the same thing
+func recentKey(item *RecentItem) string {
+	if item.File != "" {
+		return "file\x00" + strings.ToLower(item.File)
+	}
+	l, _ := item.Action.Launch()
+	return strings.ToLower(l.Kind+"\x00"+l.File) + "\x00" + l.Parameters()
+}
+
+// recentSize is the largest size of the recent menus of c, 0 without a recent
+// menu so nothing is recorded
+func recentSize(c *config.Config) (size int) {
+	for _, menu := range [][]config.Contextmenu{c.Contextmenu, c.Taskbar.Contextmenu} {
+		for _, m := range menu {
+			if m.Recent != nil && m.Recent.SizeOrDefault() > size {
+				size = m.Recent.SizeOrDefault()
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"GoShell/config"
+)
+
+func recentNames(items []RecentItem) (names []string) {
+	for _, it := range items {
+		names = append(names, it.Name)
+	}
+	return
+}
+
+func TestRecentListAdd(t *testing.T) {
+	var r RecentList
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.Add(RecentItem{Name: "notepad", Action: config.Action{OpenProcess: "notepad.exe"}}, now, 3)
+	r.Add(RecentItem{Name: "report", File: `C:\Docs\report.txt`}, now, 3)
+	// the same program with other arguments is another item
+	r.Add(RecentItem{Name: "notepad a", Action: config.Action{OpenProcess: "notepad.exe", Args: []string{"a.txt"}}}, now, 3)
+	// the same file in another case moves to the top
+	r.Add(RecentItem{Name: "Report", File: `c:\docs\REPORT.txt`}, now, 3)
+	if got, want := recentNames(r.Items), []string{"Report", "notepad a", "notepad"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// a file doesn't match an action that opens it
+	r.Add(RecentItem{Name: "open report", Action: config.Action{ShellExecute: `C:\Docs\report.txt`}}, now, 3)
+	if got, want := recentNames(r.Items), []string{"open report", "Report", "notepad a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRecentListPinned(t *testing.T) {
+	var r RecentList
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.Add(RecentItem{Name: "b", File: `C:\b.txt`}, now, 1)
+	r.SetPinned(&RecentItem{File: `C:\B.TXT`}, true)
+	r.Add(RecentItem{Name: "a", File: `C:\a.txt`}, now, 1)
+	r.Add(RecentItem{Name: "c", File: `C:\c.txt`}, now, 1)
+
+	pinned, recent := r.Menu(1)
+	if got := recentNames(pinned); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("pinned: got %q", got)
+	}
+	if got := recentNames(recent); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("recent: got %q", got)
+	}
+
+	r.Clear()
+	if got := recentNames(r.Items); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("after Clear: got %q", got)
+	}
+}
+
+func TestRecentListSave(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "GoShell", "recent.yaml")
+	r, err := LoadRecentList(file)
+	if err != nil || len(r.Items) != 0 {
+		t.Fatalf("got %v, %v", r.Items, err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.Add(RecentItem{Name: "report", File: `C:\Docs\report.txt`}, now, 5)
+	r.Add(RecentItem{Name: "calc", Action: config.Action{ShellExecute: "calc.exe"}}, now, 5)
+	if err := r.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadRecentList(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Items, r.Items) {
+		t.Errorf("got %+v, want %+v", got.Items, r.Items)
+	}
+
+	// without a file the list is only kept in memory
+	r, _ = LoadRecentList("")
+	r.Add(RecentItem{Name: "report", File: `C:\Docs\report.txt`}, now, 5)
+	if err := r.Save(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRecentFilePath(t *testing.T) {
+	t.Setenv("APPDATA", filepath.Join("C:", "Users", "a", "AppData", "Roaming"))
+	if got, want := RecentFilePath(), filepath.Join("C:", "Users", "a", "AppData", "Roaming", "GoShell", "recent.yaml"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	t.Setenv("APPDATA", "")
+	if got := RecentFilePath(); got != "" {
+		t.Errorf("without APPDATA: got %q", got)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"GoShell/config"
+)
+
+const (
+	serviceMaxRestarts = 5
+	serviceMinBackoff  = time.Second
+	serviceMaxBackoff  = time.Minute
+	// a program that ran this long counts as started, its restarts start over
+	serviceStableAfter = time.Minute
+	// the time a program gets to close its windows before it is killed
+	serviceStopTimeout = 5 * time.Second
+)
+
+// ServiceProcess is a running program of a service
+type ServiceProcess interface {
+	// Wait blocks until the program exits
+	Wait() (exitCode int, err error)
+	// Stop asks the program to exit and kills it after timeout
+	Stop(timeout time.Duration) error
+}
+
+// ServiceStarter starts the programs, a fake can replace the real processes
+type ServiceStarter interface {
+	Start(l config.Launch) (ServiceProcess, error)
+}
+
+// serviceState decides what happens after the program of a service exited
+type serviceState struct {
+	restarts int // quick exits in a row
+}
+
+// exited returns whether and when the program is started again, given up is
+// true if it exits too often in a row
+func (st *serviceState) exited(svc *config.Service, exitCode int, err error, ran time.Duration) (restart bool, delay time.Duration, givenUp bool) {
+	switch strings.ToLower(svc.Restart) {
+	case "never":
+		return false, 0, false
+	case "always":
+	default: // onFailure
+		if err == nil && exitCode == 0 {
+			return false, 0, false
+		}
+	}
+
+	if ran >= serviceStableAfter {
+		st.restarts = 0
+	}
+	max := svc.MaxRestarts
+	if max == 0 {
+		max = serviceMaxRestarts
+	}
+	if st.restarts >= max {
+		return false, 0, true
+	}
+	delay = serviceMinBackoff << st.restarts
+	if delay > serviceMaxBackoff {
+		delay = serviceMaxBackoff
+	}
+	st.restarts++
+	return true, delay, false
+}
+
+// Supervisor runs the services and restarts them
+type Supervisor struct {
+	starter ServiceStarter
+	now     func() time.Time
+	after   func(d time.Duration) <-chan time.Time
+	// called when a service is given up, from the goroutine of the service
+	onGiveUp func(svc config.Service, err error)
+
+	mu      sync.Mutex
+	running map[string]*supervised
+
+	// one Sync or Stop at a time, after Stop no service starts anymore
+	syncMu  sync.Mutex
+	stopped bool
+}
+
+type supervised struct {
+	svc     config.Service
+	stop    chan struct{}
+	done    chan struct{}
+	process ServiceProcess
+}
+
+func NewSupervisor(starter ServiceStarter) *Supervisor {
+	return &Supervisor{
+		starter:  starter,
+		now:      time.Now,
+		after:    time.After,
+		onGiveUp: func(config.Service, error) {},
+		running:  make(map[string]*supervised),
+	}
+}
+
+// Sync starts the services that are new, stops the ones that are gone and
+// restarts the ones that changed. It waits up to serviceStopTimeout for the
+// programs that are stopped, so the UI thread calls it in the background.
+// The error is the one of the programs that couldn't be stopped.
+func (sv *Supervisor) Sync(services []config.Service) error {
+	sv.syncMu.Lock()
+	defer sv.syncMu.Unlock()
+	if sv.stopped {
+		return nil
+	}
+
+	wanted := make(map[string]config.Service, len(services))
+	for _, svc := range services {
+		wanted[strings.ToLower(svc.Name)] = svc
+	}
+
+	sv.mu.Lock()
+	var stop []*supervised
+	for key, r := range sv.running {
+		if svc, ok := wanted[key]; !ok || !reflect.DeepEqual(svc, r.svc) {
+			stop = append(stop, r)
+			delete(sv.running, key)
+		}
+	}
+	sv.mu.Unlock()
+	err := sv.stopAll(stop)
+
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	for key, svc := range wanted {
+		if _, ok := sv.running[key]; ok {
+			continue
+		}
+		r := &supervised{svc: svc, stop: make(chan struct{}), done: make(chan struct{})}
+		sv.running[key] = r
+		go sv.run(r)
+	}
+	return err
+}
+
+// Stop stops every service and waits until they exited, a later Sync
+// doesn't start them again
+func (sv *Supervisor) Stop() error {
+	sv.syncMu.Lock()
+	defer sv.syncMu.Unlock()
+	sv.stopped = true
+
+	sv.mu.Lock()
+	stop := make([]*supervised, 0, len(sv.running))
+	for key, r := range sv.running {
+		stop = append(stop, r)
+		delete(sv.running, key)
+	}
+	sv.mu.Unlock()
+	return sv.stopAll(stop)
+}
+
+// stopAll stops the services at the same time, so it takes at most
+// serviceStopTimeout and not that for every service
+func (sv *Supervisor) stopAll(list []*supervised) error {
+	errs := make([]error, len(list))
+	var wg sync.WaitGroup
+	for i, r := range list {
+		close(r.stop)
+		sv.mu.Lock()
+		p := r.process
+		sv.mu.Unlock()
+		wg.Add(1)
+		go func(i int, r *supervised) {
+			defer wg.Done()
+			if p != nil {
+				if err := p.Stop(serviceStopTimeout); err != nil {
+					log.Println("service", r.svc.Name, err)
+					errs[i] = fmt.Errorf("service %s: %w", r.svc.Name, err)
+				}
+			}
+			<-r.done
+		}(i, r)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (sv *Supervisor) run(r *supervised) {
+	defer close(r.done)
+	var st serviceState
+	for {
+		started := sv.now()
+		p, err := sv.starter.Start(r.svc.Launch())
+		exitCode := 0
+		if err == nil {
+			log.Println("service", r.svc.Name, "started")
+			sv.mu.Lock()
+			r.process = p
+			sv.mu.Unlock()
+			select {
+			case <-r.stop: // stopped before the process was stored
+				p.Stop(serviceStopTimeout)
+			default:
+			}
+			exitCode, err = p.Wait()
+			sv.mu.Lock()
+			r.process = nil
+			sv.mu.Unlock()
+		}
+
+		select {
+		case <-r.stop:
+			log.Println("service", r.svc.Name, "stopped")
+			return
+		default:
+		}
+
+		restart, delay, givenUp := st.exited(&r.svc, exitCode, err, sv.now().Sub(started))
+		if err == nil {
+			err = fmt.Errorf("exit code %d", exitCode)
+		}
+		switch {
+		case givenUp:
+			log.Printf("service %s: %v, given up after %d restarts", r.svc.Name, err, st.restarts)
+			sv.onGiveUp(r.svc, err)
+			return
+		case !restart:
+			log.Printf("service %s: %v", r.svc.Name, err)
+			return
+		}
+		log.Printf("service %s: %v, restart in %s", r.svc.Name, err, delay)
+
+		select {
+		case <-r.stop:
+			return
+		case <-sv.after(delay):
+		}
+	}
+}
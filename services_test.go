@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"GoShell/config"
+)
+
+func TestServiceBackoff(t *testing.T) {
+	svc := &config.Service{Name: "a", Restart: "always", MaxRestarts: 10}
+	var st serviceState
+	want := []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second,
+		32 * time.Second, time.Minute, time.Minute, time.Minute, time.Minute,
+	}
+	for i, w := range want {
+		restart, delay, givenUp := st.exited(svc, 1, nil, time.Millisecond)
+		if !restart || givenUp || delay != w {
+			t.Errorf("exit %d: got %v, %s, %v, want a restart in %s", i+1, restart, delay, givenUp, w)
+		}
+	}
+	if restart, _, givenUp := st.exited(svc, 1, nil, time.Millisecond); restart || !givenUp {
+		t.Errorf("exit %d: got %v, %v, want given up", len(want)+1, restart, givenUp)
+	}
+}
+
+func TestServiceBackoffStable(t *testing.T) {
+	svc := &config.Service{Name: "a", Restart: "always"}
+	var st serviceState
+	for i := 0; i < 3; i++ {
+		st.exited(svc, 1, nil, time.Second)
+	}
+	// a program that ran long enough starts over with the short delay
+	if restart, delay, _ := st.exited(svc, 1, nil, serviceStableAfter); !restart || delay != serviceMinBackoff {
+		t.Errorf("got %v, %s, want a restart in %s", restart, delay, serviceMinBackoff)
+	}
+}
+
+func TestServiceRestartPolicy(t *testing.T) {
+	errStart := errors.New("not found")
+	for _, tt := range []struct {
+		restart  string
+		exitCode int
+		err      error
+		want     bool
+	}{
+		{"", 0, nil, false},
+		{"", 1, nil, true},
+		{"", 0, errStart, true},
+		{"onFailure", 0, nil, false},
+		{"OnFailure", 2, nil, true},
+		{"always", 0, nil, true},
+		{"never", 1, nil, false},
+		{"never", 0, errStart, false},
+	} {
+		var st serviceState
+		svc := &config.Service{Name: "a", Restart: tt.restart}
+		if restart, _, _ := st.exited(svc, tt.exitCode, tt.err, 0); restart != tt.want {
+			t.Errorf("restart %q, exit code %d, error %v: got %v", tt.restart, tt.exitCode, tt.err, restart)
+		}
+	}
+}
+
+// fakeProcess runs until it is stopped or exit gets a code
+type fakeProcess struct {
+	exit    chan int
+	stopped chan struct{}
+	once    sync.Once
+	// called by Stop before the process exits
+	onStop func()
+}
+
+func (p *fakeProcess) Wait() (int, error) {
+	select {
+	case code := <-p.exit:
+		return code, nil
+	case <-p.stopped:
+		return 0, nil
+	}
+}
+
+func (p *fakeProcess) Stop(timeout time.Duration) error {
+	if p.onStop != nil {
+		p.onStop()
+	}
+	p.once.Do(func() { close(p.stopped) })
+	return nil
+}
+
+// fakeStarter records the starts, exitCode < 0 lets the processes run
+type fakeStarter struct {
+	exitCode int
+	onStop   func()
+	starts   chan string
+
+	mu    sync.Mutex
+	procs map[string]*fakeProcess // the last one of every service
+}
+
+func newFakeStarter(exitCode int) *fakeStarter {
+	return &fakeStarter{exitCode: exitCode, starts: make(chan string, 100), procs: map[string]*fakeProcess{}}
+}
+
+func (f *fakeStarter) Start(l config.Launch) (ServiceProcess, error) {
+	p := &fakeProcess{exit: make(chan int, 1), stopped: make(chan struct{}), onStop: f.onStop}
+	if f.exitCode >= 0 {
+		p.exit <- f.exitCode
+	}
+	f.mu.Lock()
+	f.procs[l.File] = p
+	f.mu.Unlock()
+	f.starts <- l.File
+	return p, nil
+}
+
+// waitStarts returns the programs of the next n starts, sorted
+func (f *fakeStarter) waitStarts(t *testing.T, n int) []string {
+	t.Helper()
+	var started []string
+	for i := 0; i < n; i++ {
+		select {
+		case name := <-f.starts:
+			started = append(started, name)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d starts: %q", i, n, started)
+		}
+	}
+	sort.Strings(started)
+	return started
+}
+
+func (f *fakeStarter) noMoreStarts(t *testing.T) {
+	t.Helper()
+	select {
+	case name := <-f.starts:
+		t.Errorf("unexpected start of %s", name)
+	default:
+	}
+}
+
+func (f *fakeStarter) isStopped(name string) bool {
+	f.mu.Lock()
+	p := f.procs[name]
+	f.mu.Unlock()
+	select {
+	case <-p.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+// newTestSupervisor has no delays between the restarts
+func newTestSupervisor(starter ServiceStarter) *Supervisor {
+	sv := NewSupervisor(starter)
+	sv.after = func(time.Duration) <-chan time.Time {
+		c := make(chan time.Time, 1)
+		c <- time.Time{}
+		return c
+	}
+	return sv
+}
+
+func TestSupervisorGivesUp(t *testing.T) {
+	starter := newFakeStarter(1)
+	sv := newTestSupervisor(starter)
+	givenUp := make(chan string, 1)
+	sv.onGiveUp = func(svc config.Service, err error) { givenUp <- svc.Name }
+
+	sv.Sync([]config.Service{{Name: "crash", Program: "crash.exe"}})
+	select {
+	case name := <-givenUp:
+		if name != "crash" {
+			t.Errorf("given up %q", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("not given up")
+	}
+	// the first start and serviceMaxRestarts restarts
+	if got := len(starter.starts); got != 1+serviceMaxRestarts {
+		t.Errorf("started %d times, want %d", got, 1+serviceMaxRestarts)
+	}
+	sv.Stop()
+}
+
+func TestSupervisorSync(t *testing.T) {
+	starter := newFakeStarter(-1)
+	sv := newTestSupervisor(starter)
+
+	sv.Sync([]config.Service{
+		{Name: "a", Program: "a.exe"},
+		{Name: "b", Program: "b.exe"},
+	})
+	if got := starter.waitStarts(t, 2); !equalStrings(got, "a.exe", "b.exe") {
+		t.Errorf("started %q", got)
+	}
+
+	// a stays, b changed and is started again, c is new
+	if err := sv.Sync([]config.Service{
+		{Name: "a", Program: "a.exe"},
+		{Name: "b", Program: "b2.exe"},
+		{Name: "c", Program: "c.exe"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := starter.waitStarts(t, 2); !equalStrings(got, "b2.exe", "c.exe") {
+		t.Errorf("started %q", got)
+	}
+	starter.noMoreStarts(t)
+	if !starter.isStopped("b.exe") || starter.isStopped("a.exe") {
+		t.Errorf("stopped a: %v, b: %v, want only b", starter.isStopped("a.exe"), starter.isStopped("b.exe"))
+	}
+
+	// the same services change nothing
+	sv.Sync([]config.Service{
+		{Name: "a", Program: "a.exe"},
+		{Name: "b", Program: "b2.exe"},
+		{Name: "c", Program: "c.exe"},
+	})
+	starter.noMoreStarts(t)
+
+	sv.Sync(nil)
+	for _, name := range []string{"a.exe", "b2.exe", "c.exe"} {
+		if !starter.isStopped(name) {
+			t.Errorf("%s is still running", name)
+		}
+	}
+
+	// nothing starts after Stop
+	sv.Stop()
+	sv.Sync([]config.Service{{Name: "d", Program: "d.exe"}})
+	starter.noMoreStarts(t)
+}
+
+// the services are stopped at the same time: every Stop waits until all of
+// them were asked to stop
+func TestSupervisorStopsConcurrently(t *testing.T) {
+	const n = 4
+	var (
+		mu      sync.Mutex
+		waiting int
+		all     = make(chan struct{})
+	)
+	starter := newFakeStarter(-1)
+	starter.onStop = func() {
+		mu.Lock()
+		waiting++
+		if waiting == n {
+			close(all)
+		}
+		mu.Unlock()
+		select {
+		case <-all:
+		case <-time.After(5 * time.Second):
+			t.Error("the services are stopped one after another")
+		}
+	}
+	sv := newTestSupervisor(starter)
+
+	var services []config.Service
+	for _, name := range []string{"a", "b", "c", "d"} {
+		services = append(services, config.Service{Name: name, Program: name + ".exe"})
+	}
+	sv.Sync(services)
+	starter.waitStarts(t, n)
+	sv.Stop()
+}
+
+func equalStrings(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"GoShell/config"
+	lnk "github.com/parsiya/golnk"
+)
+
+// Shortcut is what a .lnk file starts
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-shllink/16cb4ca1-9339-4d0c-a68d-bf1d6cc0f943
+type Shortcut struct {
+	File string // the .lnk itself
+	// the program or file, empty if only the shell can open the shortcut
+	// (apps of the Store, the Control Panel, ...)
+	Target      string
+	CommandLine string   // the arguments as they are in the shortcut
+	Args        []string // CommandLine split like the program does
+	Workdir     string
+	Show        string // "", "minimized" or "maximized" like config.Action.Show
+	Icon        string
+	IconIndex   int32
+	Description string
+}
+
+// ReadShortcut reads a .lnk file, expand replaces the %variables% of the
+// paths in it. A target that doesn't exist is left to the shell, which
+// can find moved files.
+func ReadShortcut(file string, expand func(string) string) (Shortcut, error) {
+	l, err := lnk.File(file)
+	if err != nil {
+		return Shortcut{File: file}, err
+	}
+	s := resolveShortcut(l, file, expand)
+	if s.Target != "" {
+		if _, err := os.Stat(s.Target); err != nil {
+			s.Target = ""
+		}
+	}
+	return s, nil
+}
+
+// resolveShortcut returns the launch spec of a parsed .lnk, the path of the
+// target is taken from the first of these that is set:
+// the environment block, the local path, the network path, the relative path
+func resolveShortcut(l lnk.LnkFile, file string, expand func(string) string) Shortcut {
+	s := Shortcut{
+		File:        file,
+		CommandLine: l.StringData.CommandLineArguments,
+		Args:        config.SplitArgs(l.StringData.CommandLineArguments),
+		Workdir:     expand(l.StringData.WorkingDir),
+		Icon:        expand(l.StringData.IconLocation),
+		IconIndex:   l.Header.IconIndex,
+		Description: l.StringData.NameString,
+	}
+	switch l.Header.ShowCommand {
+	case "SW_SHOWMAXIMIZED":
+		s.Show = "maximized"
+	case "SW_SHOWMINNOACTIVE":
+		s.Show = "minimized"
+	}
+
+	info := l.LinkInfo
+	local := firstOf(info.LocalBasePathUnicode, info.LocalBasePath)
+	suffix := firstOf(info.CommonPathSuffixUnicode, info.CommonPathSuffix)
+	net := firstOf(info.NetworkRelativeLink.NetNameUnicode, info.NetworkRelativeLink.NetName)
+	for _, block := range l.DataBlocks.Blocks {
+		switch block.Type {
+		case "EnvironmentVariableDataBlock":
+			if target := dataBlockPath(block.Data); target != "" {
+				s.Target = expand(target)
+			}
+		case "IconEnvironmentDataBlock":
+			if icon := dataBlockPath(block.Data); icon != "" {
+				s.Icon = expand(icon)
+			}
+		}
+	}
+	switch {
+	case s.Target != "":
+	case local != "":
+		s.Target = joinLinkPath(local, suffix)
+	case net != "":
+		s.Target = joinLinkPath(net, suffix)
+	case l.StringData.RelativePath != "":
+		s.Target = filepath.Join(filepath.Dir(file), l.StringData.RelativePath)
+	}
+	return s
+}
+
+// Action returns the action that starts the shortcut, without a target the
+// shell opens the .lnk itself
+func (s *Shortcut) Action() config.Action {
+	if s.Target == "" {
+		return config.Action{ShellExecute: s.File}
+	}
+	a := config.Action{CommandLine: s.CommandLine, Workdir: s.Workdir, Show: s.Show}
+	if strings.EqualFold(filepath.Ext(s.Target), ".exe") {
+		a.OpenProcess = s.Target
+	} else {
+		a.ShellExecute = s.Target // a document or a folder
+	}
+	return a
+}
+
+// dataBlockPath returns the path of an EnvironmentVariableDataBlock or an
+// IconEnvironmentDataBlock: 260 bytes ANSI, then 520 bytes unicode
+func dataBlockPath(data []byte) string {
+	if len(data) < 260+520 {
+		return ""
+	}
+	u := make([]uint16, 260)
+	for i := range u {
+		u[i] = uint16(data[260+2*i]) | uint16(data[260+2*i+1])<<8
+	}
+	for i, c := range u {
+		if c == 0 {
+			u = u[:i]
+			break
+		}
+	}
+	if len(u) != 0 {
+		return string(utf16.Decode(u))
+	}
+	ansi := data[:260]
+	for i, c := range ansi {
+		if c == 0 {
+			ansi = ansi[:i]
+			break
+		}
+	}
+	return string(ansi)
+}
+
+// joinLinkPath joins the base path and the suffix of a LinkInfo, the base
+// of a network share has no separator at the end
+func joinLinkPath(base, suffix string) string {
+	if suffix == "" || strings.HasSuffix(base, `\`) {
+		return base + suffix
+	}
+	return base + `\` + suffix
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -1,41 +1,167 @@
+//go:build windows
+
 package main
 
 import (
+	"fmt"
+	"io"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 
-	lnk "github.com/parsiya/golnk"
+	"GoShell/config"
+	"github.com/leaanthony/winc/w32"
+	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/registry"
 )
 
+// the Run keys in the order they start, HKLM before HKCU
+var startupKeys = []struct {
+	root    registry.Key
+	name    string // root for StartupItem.Source
+	path    string
+	runOnce bool
+}{
+	{registry.LOCAL_MACHINE, "HKLM", `Software\Microsoft\Windows\CurrentVersion\Run`, false},
+	{registry.LOCAL_MACHINE, "HKLM", `Software\Microsoft\Windows\CurrentVersion\RunOnce`, true},
+	{registry.CURRENT_USER, "HKCU", `Software\Microsoft\Windows\CurrentVersion\Run`, false},
+	{registry.CURRENT_USER, "HKCU", `Software\Microsoft\Windows\CurrentVersion\RunOnce`, true},
+}
+
+// where the Task Manager keeps which items are turned off, in the same root
+// as the Run key or HKLM for the startup folder of all users
+const (
+	approvedRun    = `Software\Microsoft\Windows\CurrentVersion\Explorer\StartupApproved\Run`
+	approvedFolder = `Software\Microsoft\Windows\CurrentVersion\Explorer\StartupApproved\StartupFolder`
+)
+
 // https://github.com/lsdev/litestep-/blob/bbc1182d8abcd660e1c91c2207aa27b940898c9b/litestep/StartupRunner.cpp#L143
 // https://github.com/cairoshell/ManagedShell/blob/5e7e0ed524c6d196032161eec11888c59c6175b4/src/ManagedShell.Common/SupportingClasses/StartupRunner.cs#L33
-func startup() {
-	startFromRegistry(registry.LOCAL_MACHINE, `Software\Microsoft\Windows\CurrentVersion\Run`, false)
-	startFromRegistry(registry.LOCAL_MACHINE, `Software\Microsoft\Windows\CurrentVersion\RunOnce`, true)
-	startFromRegistry(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run`, false)
-	startFromRegistry(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\RunOnce`, true)
-
-	startFromFolder(getKnownFolderPath(FOLDERIDs["FOLDERID_CommonStartup"]))
-	startFromFolder(getKnownFolderPath(FOLDERIDs["FOLDERID_Startup"]))
+// c is the config when startup began, a reload doesn't change it halfway
+func startup(c *config.Config) {
+	// ShellExecute can hand a file to shell extensions, they need COM in a
+	// single threaded apartment like the UI thread has
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := windows.CoInitializeEx(0, windows.COINIT_APARTMENTTHREADED); err != nil {
+		log.Println("startup: CoInitializeEx:", err)
+	} else {
+		defer windows.CoUninitialize()
+	}
+
+	start := time.Now()
+	items, skipped := filterStartup(&c.Startup, startupItems())
+	applyStartupRules(&c.Startup, items)
+
+	s := &StartupScheduler{
+		Parallel: c.Startup.Parallel,
+		Settle:   time.Duration(c.Startup.Settle),
+		Clock:    realClock{},
+		Launch: func(item StartupItem) (uint32, error) {
+			return launchStartupItem(c, item)
+		},
+		WaitIdle: waitIdle,
+	}
+	started := s.Run(items)
+
+	var report strings.Builder
+	writeStartupReport(&report, describeStartup(c, started), describeStartup(c, skipped), start, false)
+	log.Print("startup:\n", report.String())
+}
+
+// startupDryRun writes what startup would do to w, without starting anything
+func startupDryRun(w io.Writer, c *config.Config) error {
+	clock := &dryClock{}
+	items, skipped := filterStartup(&c.Startup, startupItems())
+	applyStartupRules(&c.Startup, items)
+
+	s := &StartupScheduler{
+		Parallel: c.Startup.Parallel,
+		Settle:   time.Duration(c.Startup.Settle),
+		Clock:    clock,
+		Launch: func(item StartupItem) (uint32, error) {
+			_, err := startupLaunch(c, item)
+			return 0, err
+		},
+	}
+	started := s.Run(items)
+	return writeStartupReport(w, describeStartup(c, started), describeStartup(c, skipped), time.Time{}, true)
+}
+
+// describeStartup adds the status and the command line to the results
+func describeStartup(c *config.Config, results []StartupResult) []StartupResult {
+	for i := range results {
+		r := &results[i]
+		if r.Order != 0 {
+			r.Status = startupStatus(&c.Startup, r.Item)
+		}
+		if l, err := startupLaunch(c, r.Item); err == nil {
+			r.CommandLine = strings.TrimSpace(config.QuoteArg(l.File) + " " + l.Parameters())
+		}
+	}
+	return results
+}
+
+// startupItems returns the registry before the folders
+func startupItems() (items []StartupItem) {
+	for _, key := range startupKeys {
+		list := itemsFromRegistry(key.root, key.name, key.path, key.runOnce)
+		if !key.runOnce { // RunOnce can't be turned off
+			setDisabled(list, key.root, approvedRun)
+		}
+		items = append(items, list...)
+	}
+
+	list := itemsFromFolder(getKnownFolderPath(FOLDERIDs["FOLDERID_CommonStartup"]))
+	setDisabled(list, registry.LOCAL_MACHINE, approvedFolder)
+	items = append(items, list...)
+
+	list = itemsFromFolder(getKnownFolderPath(FOLDERIDs["FOLDERID_Startup"]))
+	setDisabled(list, registry.CURRENT_USER, approvedFolder)
+	return append(items, list...)
+}
+
+// setDisabled marks the items that are turned off in the StartupApproved key
+func setDisabled(items []StartupItem, loc registry.Key, path string) {
+	k, err := registry.OpenKey(loc, path, registry.QUERY_VALUE)
+	if err != nil {
+		return // nothing was ever turned off
+	}
+	defer k.Close()
+
+	for i := range items {
+		data, _, err := k.GetBinaryValue(items[i].Name)
+		if err == nil && !startupApproved(data) {
+			items[i].Disabled = true
+		}
+	}
 }
 
-func startFromFolder(path string) {
+func itemsFromFolder(path string) (items []StartupItem) {
 	files, err := os.ReadDir(path)
 	if err != nil {
 		log.Println(err)
 		return
 	}
 	for _, f := range files {
-		go RunProgram(filepath.Join(path, f.Name()))
+		if strings.EqualFold(filepath.Ext(f.Name()), ".ini") {
+			continue // desktop.ini
+		}
+		items = append(items, StartupItem{
+			Name:    f.Name(),
+			Source:  path,
+			Command: filepath.Join(path, f.Name()),
+			Folder:  true,
+		})
 	}
+	return
 }
 
-func startFromRegistry(loc registry.Key, path string, deleteValue bool) {
-	k, err := registry.OpenKey(loc, path, registry.SET_VALUE|registry.QUERY_VALUE)
+func itemsFromRegistry(loc registry.Key, name, path string, runOnce bool) (items []StartupItem) {
+	k, err := registry.OpenKey(loc, path, registry.QUERY_VALUE)
 	if err != nil {
 		log.Println(err)
 		return
@@ -47,30 +173,106 @@ func startFromRegistry(loc registry.Key, path string, deleteValue bool) {
 		return
 	}
 	for _, param := range params {
-		s, _, err := k.GetStringValue(param)
+		s, valtype, err := k.GetStringValue(param)
 		if err != nil {
 			continue
 		}
+		if valtype == registry.EXPAND_SZ {
+			s = ExpandEnvironment(s)
+		}
+		items = append(items, StartupItem{
+			Name:    param,
+			Source:  name + `\` + path,
+			Command: s,
+			RunOnce: runOnce,
+		})
+	}
+	return
+}
 
-		if deleteValue {
-			k.DeleteValue(param)
+// startupLaunch returns how an item is started
+func startupLaunch(c *config.Config, item StartupItem) (config.Launch, error) {
+	if !item.Folder {
+		program, params := config.SplitCommandLine(item.Command, isFile)
+		if program == "" {
+			return config.Launch{}, fmt.Errorf("empty command line")
+		}
+		switch strings.ToLower(filepath.Ext(program)) {
+		case ".exe", ".com", "":
+			return config.Launch{Kind: "openProcess", File: program, CommandLine: params}, nil
+		}
+		// e.g. a .bat or a .vbs
+		return config.Launch{Kind: "shellExecute", File: program, CommandLine: params}, nil
+	}
+	var a config.Action
+	if strings.EqualFold(filepath.Ext(item.Command), ".lnk") {
+		sc, err := ReadShortcut(item.Command, ExpandEnvironment)
+		if err != nil {
+			log.Println(err) // the shell opens it
 		}
+		a = sc.Action()
+	} else {
+		a = config.FileAction(c.FileHandlers, item.Command)
+	}
+	l, _ := a.Launch()
+	return l, nil
+}
 
-		// https://stackoverflow.com/a/12076082
-		go exec.Command("rundll32.exe", "url.dll,FileProtocolHandler", s).Start()
+func launchStartupItem(c *config.Config, item StartupItem) (uint32, error) {
+	l, err := startupLaunch(c, item)
+	if err != nil {
+		return 0, err
 	}
+	if item.RunOnce {
+		// deleted before the start like Explorer does, so a program that
+		// crashes the shell doesn't run again
+		for _, key := range startupKeys {
+			if key.runOnce && item.Source == key.name+`\`+key.path {
+				if k, err := registry.OpenKey(key.root, key.path, registry.SET_VALUE); err == nil {
+					k.DeleteValue(item.Name)
+					k.Close()
+				}
+			}
+		}
+	}
+	return processLauncher{}.Start(l)
 }
 
-func RunProgram(file string) {
-	switch strings.ToLower(filepath.Ext(file)) {
-	case ".lnk":
-		Lnk, err := lnk.File(file)
-		if err != nil {
-			return
+func isFile(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && !fi.IsDir()
+}
+
+const (
+	idleUsage    = 20 // percent of the CPU
+	idleSamples  = 3  // seconds in a row below idleUsage
+	idleMaxDelay = time.Minute
+)
+
+// waitIdle returns when the CPU usage is low for a few seconds, at the latest
+// after idleMaxDelay
+// https://docs.microsoft.com/en-us/windows/win32/api/processthreadsapi/nf-processthreadsapi-getsystemtimes
+func waitIdle() {
+	times := func() (idle, total uint64) {
+		var i, k, u w32.FILETIME
+		if !w32.GetSystemTimes(&i, &k, &u) {
+			return 0, 0
+		}
+		ft := func(t w32.FILETIME) uint64 { return uint64(t.DwHighDateTime)<<32 | uint64(t.DwLowDateTime) }
+		return ft(i), ft(k) + ft(u) // the kernel time contains the idle time
+	}
+
+	deadline := time.Now().Add(idleMaxDelay)
+	lastIdle, lastTotal := times()
+	samples := 0
+	for samples < idleSamples && time.Now().Before(deadline) {
+		time.Sleep(time.Second)
+		idle, total := times()
+		if total > lastTotal && 100*(1-float64(idle-lastIdle)/float64(total-lastTotal)) < idleUsage {
+			samples++
+		} else {
+			samples = 0
 		}
-		exec.Command(Lnk.LinkInfo.LocalBasePath, Lnk.StringData.CommandLineArguments).Start()
-	case ".ini": // desktop.ini
-	default:
-		exec.Command(file).Start()
+		lastIdle, lastTotal = idle, total
 	}
 }
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"GoShell/config"
+)
+
+// StartupItem is a program of the Run keys or the startup folders
+type StartupItem struct {
+	Name    string // value name or file name
+	Source  string // e.g. HKLM\Software\Microsoft\Windows\CurrentVersion\Run or the folder
+	Command string // the value or the path of the file
+	Folder  bool   // from a startup folder
+	RunOnce bool   // the value is deleted when it is started
+	// turned off in the Task Manager (StartupApproved)
+	Disabled bool
+
+	Delay    time.Duration
+	Priority int
+	Idle     bool
+}
+
+// StartupResult is a line of the startup report
+type StartupResult struct {
+	Item        StartupItem
+	Status      string
+	CommandLine string    // what was started, the Command resolved
+	Order       int       // 1 for the first started item, 0 if it didn't start
+	Time        time.Time // when it was started
+	PID         uint32    // 0 if it is not known
+	Err         error
+}
+
+// startupApproved reads a value of the StartupApproved keys, the Task Manager
+// sets the lowest bit of the first byte when an item is turned off. The other
+// bytes are the time it was turned off.
+func startupApproved(data []byte) bool {
+	return len(data) == 0 || data[0]&1 == 0
+}
+
+// Clock is the time for the StartupScheduler, a fake one makes it deterministic
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// StartupScheduler starts the startup items one after another: a program
+// holds one of the Parallel slots for Settle after it was started.
+type StartupScheduler struct {
+	Parallel int
+	Settle   time.Duration
+	Clock    Clock
+	Launch   func(item StartupItem) (pid uint32, err error)
+	// blocks until the computer is idle, before the items with Idle
+	WaitIdle func()
+}
+
+// the Status of a StartupResult
+const (
+	startupStart    = "start"
+	startupIncluded = "included" // starts because of startup.include
+	startupDisabled = "disabled" // in the Task Manager
+	startupExcluded = "excluded" // by startup.exclude
+)
+
+// startupStatus says if the item starts: include wins over exclude and over the
+// Task Manager
+func startupStatus(c *config.StartupConfig, item StartupItem) string {
+	switch {
+	case (item.Disabled || config.MatchNames(c.Exclude, item.Name)) && config.MatchNames(c.Include, item.Name):
+		return startupIncluded
+	case item.Disabled:
+		return startupDisabled
+	case config.MatchNames(c.Exclude, item.Name):
+		return startupExcluded
+	}
+	return startupStart
+}
+
+// filterStartup returns the items that start and the results of the skipped ones
+func filterStartup(c *config.StartupConfig, items []StartupItem) (start []StartupItem, skipped []StartupResult) {
+	for _, item := range items {
+		switch status := startupStatus(c, item); status {
+		case startupStart, startupIncluded:
+			start = append(start, item)
+		default:
+			skipped = append(skipped, StartupResult{Item: item, Status: status})
+		}
+	}
+	return
+}
+
+// applyStartupRules sets delay, priority and stage of the items from the first
+// rule with a matching name
+func applyStartupRules(c *config.StartupConfig, items []StartupItem) {
+	for i := range items {
+		for _, rule := range c.Items {
+			if config.MatchName(rule.Name, items[i].Name) {
+				items[i].Delay = time.Duration(rule.Delay)
+				items[i].Priority = rule.Priority
+				items[i].Idle = rule.Idle
+				break
+			}
+		}
+	}
+}
+
+// planStartup returns the items in the order they start: the idle stage last,
+// then by delay and priority. Otherwise the order of items stays, that is
+// HKLM before HKCU and the registry before the folders.
+func planStartup(items []StartupItem) []StartupItem {
+	plan := append([]StartupItem(nil), items...)
+	sort.SliceStable(plan, func(i, j int) bool {
+		a, b := plan[i], plan[j]
+		switch {
+		case a.Idle != b.Idle:
+			return !a.Idle
+		case a.Delay != b.Delay:
+			return a.Delay < b.Delay
+		}
+		return a.Priority > b.Priority
+	})
+	return plan
+}
+
+// Run starts the items and returns after the last one was started, with a
+// result for each item in the order they were started
+func (s *StartupScheduler) Run(items []StartupItem) (results []StartupResult) {
+	parallel := s.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	start := s.Clock.Now()
+	free := make([]time.Time, parallel) // when each slot is free again
+	idle := false
+
+	for _, item := range planStartup(items) {
+		if item.Idle && !idle {
+			if s.WaitIdle != nil {
+				s.WaitIdle()
+			}
+			idle = true
+			start = s.Clock.Now()
+		}
+
+		// the slot that is free first
+		slot := 0
+		for i := range free {
+			if free[i].Before(free[slot]) {
+				slot = i
+			}
+		}
+		at := start.Add(item.Delay)
+		if free[slot].After(at) {
+			at = free[slot]
+		}
+		if wait := at.Sub(s.Clock.Now()); wait > 0 {
+			s.Clock.Sleep(wait)
+		}
+
+		r := StartupResult{Item: item, Status: startupStart, Order: len(results) + 1, Time: s.Clock.Now()}
+		r.PID, r.Err = s.Launch(item)
+		results = append(results, r)
+		free[slot] = s.Clock.Now().Add(s.Settle)
+	}
+	return
+}
+
+// dryClock is a Clock that only counts, for -startup-dry-run
+type dryClock struct {
+	now time.Time
+}
+
+func (c *dryClock) Now() time.Time        { return c.now }
+func (c *dryClock) Sleep(d time.Duration) { c.now = c.now.Add(d) }
+
+// writeStartupReport writes a table of the started items in their order and
+// the skipped ones after them. With dry the times are relative to the start
+// and don't contain the wait for the idle stage.
+func writeStartupReport(w io.Writer, started, skipped []StartupResult, start time.Time, dry bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\ttime\tstatus\tsource\tname\tcommand line\tpid")
+	for _, r := range append(append([]StartupResult(nil), started...), skipped...) {
+		order, at, pid := "-", "-", "-"
+		if r.Order != 0 {
+			order = strconv.Itoa(r.Order)
+			if dry {
+				at = "+" + r.Time.Sub(start).String()
+				if r.Item.Idle {
+					at += " +idle" // the wait isn't known before
+				}
+			} else {
+				at = r.Time.Format("15:04:05.000")
+			}
+		}
+		if r.PID != 0 {
+			pid = strconv.FormatUint(uint64(r.PID), 10)
+		}
+		status := r.Status
+		if r.Err != nil {
+			status = "error: " + r.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", order, at, status, r.Item.Source, r.Item.Name, r.CommandLine, pid)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"GoShell/config"
+)
+
+// fakeClock only moves on Sleep and records the sleeps
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+}
+
+// fakeLaunch records when each item was launched
+type fakeLaunch struct {
+	clock *fakeClock
+	names []string
+	times map[string]time.Duration // since the start
+	start time.Time
+	err   map[string]error
+}
+
+func newFakeLaunch(clock *fakeClock) *fakeLaunch {
+	return &fakeLaunch{clock: clock, times: map[string]time.Duration{}, start: clock.now, err: map[string]error{}}
+}
+
+func (f *fakeLaunch) launch(item StartupItem) (uint32, error) {
+	f.names = append(f.names, item.Name)
+	f.times[item.Name] = f.clock.now.Sub(f.start)
+	return uint32(len(f.names)), f.err[item.Name]
+}
+
+func newTestScheduler(parallel int, settle time.Duration) (*StartupScheduler, *fakeClock, *fakeLaunch) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)}
+	launch := newFakeLaunch(clock)
+	return &StartupScheduler{Parallel: parallel, Settle: settle, Clock: clock, Launch: launch.launch}, clock, launch
+}
+
+func startupNames(names ...string) (items []StartupItem) {
+	for _, name := range names {
+		items = append(items, StartupItem{Name: name})
+	}
+	return
+}
+
+func TestStartupSchedulerSettle(t *testing.T) {
+	s, _, launch := newTestScheduler(1, 2*time.Second)
+	results := s.Run(startupNames("a", "b", "c"))
+
+	want := map[string]time.Duration{"a": 0, "b": 2 * time.Second, "c": 4 * time.Second}
+	if !reflect.DeepEqual(launch.times, want) {
+		t.Errorf("got %v, want %v", launch.times, want)
+	}
+	for i, r := range results {
+		if r.Order != i+1 || r.PID != uint32(i+1) || r.Status != startupStart {
+			t.Errorf("result %d: got %+v", i, r)
+		}
+	}
+}
+
+func TestStartupSchedulerParallel(t *testing.T) {
+	s, _, launch := newTestScheduler(2, 3*time.Second)
+	s.Run(startupNames("a", "b", "c", "d", "e"))
+
+	// two slots: a and b at once, c and d when they settled, e after c
+	want := map[string]time.Duration{
+		"a": 0, "b": 0,
+		"c": 3 * time.Second, "d": 3 * time.Second,
+		"e": 6 * time.Second,
+	}
+	if !reflect.DeepEqual(launch.times, want) {
+		t.Errorf("got %v, want %v", launch.times, want)
+	}
+	// at no time more than Parallel programs are settling
+	for _, name := range launch.names {
+		at, settling := launch.times[name], 0
+		for _, other := range launch.names {
+			if o := launch.times[other]; o <= at && at < o+s.Settle {
+				settling++
+			}
+		}
+		if settling > s.Parallel {
+			t.Errorf("%d programs settle at %s", settling, at)
+		}
+	}
+}
+
+func TestStartupSchedulerDelay(t *testing.T) {
+	s, clock, launch := newTestScheduler(1, time.Second)
+	items := startupNames("slow", "a", "b", "late")
+	items[0].Delay = 10 * time.Second
+	items[3].Delay = 30 * time.Second
+	items[3].Priority = 1
+	waited := time.Duration(0)
+	s.WaitIdle = func() {
+		waited = clock.now.Sub(launch.start)
+		clock.Sleep(time.Minute)
+	}
+	idle := StartupItem{Name: "idle", Idle: true}
+	s.Run(append([]StartupItem{idle}, items...))
+
+	if want := []string{"a", "b", "slow", "late", "idle"}; !reflect.DeepEqual(launch.names, want) {
+		t.Errorf("order: got %q, want %q", launch.names, want)
+	}
+	want := map[string]time.Duration{
+		"a": 0, "b": time.Second, "slow": 10 * time.Second, "late": 30 * time.Second,
+		"idle": 30*time.Second + time.Minute, // after the wait for idle, late settled meanwhile
+	}
+	if !reflect.DeepEqual(launch.times, want) {
+		t.Errorf("got %v, want %v", launch.times, want)
+	}
+	if waited != 30*time.Second {
+		t.Errorf("waited for idle after %s", waited)
+	}
+}
+
+func TestPlanStartup(t *testing.T) {
+	items := startupNames("a", "b", "c", "d")
+	items[1].Priority = 5
+	items[2].Delay = time.Second
+	items[3].Priority = 5
+	var got []string
+	for _, item := range planStartup(items) {
+		got = append(got, item.Name)
+	}
+	// the same priority keeps the order of the items
+	if want := []string{"b", "d", "a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// the items without rules start and are reported in the order startupItems
+// reads them: HKLM before HKCU and the registry before the folders
+func TestStartupReportOrder(t *testing.T) {
+	const run = `\Software\Microsoft\Windows\CurrentVersion\Run`
+	items := []StartupItem{
+		{Name: "Defender", Source: "HKLM" + run, Command: `"C:\Program Files\Windows Defender\MSASCuiL.exe"`},
+		{Name: "Audio", Source: "HKLM" + run, Command: `C:\Windows\System32\audio.exe -s`},
+		{Name: "Update", Source: "HKLM" + run + "Once", Command: "update.exe", RunOnce: true},
+		{Name: "Teams", Source: "HKCU" + run, Command: "teams.exe"},
+		{Name: "Old", Source: "HKCU" + run, Command: "old.exe", Disabled: true},
+		{Name: "Sync", Source: `C:\ProgramData\Microsoft\Windows\Start Menu\Programs\Startup`, Command: "sync.lnk", Folder: true},
+		{Name: "Notes", Source: `C:\Users\a\AppData\Roaming\Microsoft\Windows\Start Menu\Programs\Startup`, Command: "notes.lnk", Folder: true},
+	}
+	c := &config.StartupConfig{Exclude: []string{"Audio"}}
+	start, skipped := filterStartup(c, items)
+	applyStartupRules(c, start)
+
+	s, _, launch := newTestScheduler(1, 500*time.Millisecond)
+	launch.err["Teams"] = errors.New("not found")
+	started := s.Run(start)
+
+	var report strings.Builder
+	if err := writeStartupReport(&report, started, skipped, launch.start, true); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(report.String()), "\n")[1:] {
+		f := strings.Fields(line)
+		got = append(got, strings.Join(f[:3], " "))
+	}
+	want := []string{
+		"1 +0s start",
+		"2 +500ms start",
+		"3 +1s error:",
+		"4 +1.5s start",
+		"5 +2s start",
+		"- - excluded",
+		"- - disabled",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%s", report.String())
+	}
+	if want := []string{"Defender", "Update", "Teams", "Sync", "Notes"}; !reflect.DeepEqual(launch.names, want) {
+		t.Errorf("order: got %q, want %q", launch.names, want)
+	}
+	for i, name := range []string{"Defender", "Update", "Teams", "Sync", "Notes", "Audio", "Old"} {
+		if line := strings.Split(report.String(), "\n")[i+1]; !strings.Contains(line, name) {
+			t.Errorf("line %d: got %q, want %s", i+1, line, name)
+		}
+	}
+}
@@ -1,3 +1,5 @@
+//go:build windows
+
 package main
 
 import (
@@ -78,9 +80,9 @@ func (tl *taskList) Add(parent winc.Controller, hWnd uintptr) {
 			// TODO: more options Border, Background
 
 			p.Canvas.DrawFillRect(
-				winc.NewRect(0, 0, config.Taskbar.Button.Size.Width, config.Taskbar.Button.Size.Height),
+				winc.NewRect(0, 0, cfg.Taskbar.Button.Size.Width, cfg.Taskbar.Button.Size.Height),
 				winc.NewPen(w32.PS_GEOMETRIC, 0, winc.NewSolidColorBrush(winc.RGB(24, 24, 24))),
-				winc.NewSolidColorBrush(winc.RGB(byte(config.Taskbar.Button.Bgcolor.R), byte(config.Taskbar.Button.Bgcolor.G), byte(config.Taskbar.Button.Bgcolor.B))),
+				winc.NewSolidColorBrush(rgb(cfg.Taskbar.Button.Bgcolor)),
 			)
 
 			// Icon
@@ -88,15 +90,15 @@ func (tl *taskList) Add(parent winc.Controller, hWnd uintptr) {
 			if t.Icon != 0 {
 				left = 34
 				var iconSize = 24
-				p.Canvas.DrawIconEx(winc.NewIcon(t.Icon), int32((config.Taskbar.Height-iconSize)/2), int32((config.Taskbar.Height-iconSize)/2), int32(iconSize), int32(iconSize), 0, 0, w32.DI_NORMAL)
+				p.Canvas.DrawIconEx(winc.NewIcon(t.Icon), int32((cfg.Taskbar.Height-iconSize)/2), int32((cfg.Taskbar.Height-iconSize)/2), int32(iconSize), int32(iconSize), 0, 0, w32.DI_NORMAL)
 			}
 
 			// Text
 			text := arg.Sender.Text()
-			rc := winc.NewRect(left, 0, config.Taskbar.Button.Size.Width, config.Taskbar.Button.Size.Height)
-			color := winc.RGB(byte(config.Taskbar.Button.Textcolor.R), byte(config.Taskbar.Button.Textcolor.G), byte(config.Taskbar.Button.Textcolor.B))
+			rc := winc.NewRect(left, 0, cfg.Taskbar.Button.Size.Width, cfg.Taskbar.Button.Size.Height)
+			color := rgb(cfg.Taskbar.Button.Textcolor)
 
-			logfont, err := winc.NewLogFont(winc.LogFontDesc{Name: config.Taskbar.FontFamily, Height: config.Taskbar.FontSize})
+			logfont, err := winc.NewLogFont(winc.LogFontDesc{Name: cfg.Taskbar.FontFamily, Height: cfg.Taskbar.FontSize})
 			if err != nil {
 				log.Println(err)
 			}
@@ -115,16 +117,10 @@ func (tl *taskList) Add(parent winc.Controller, hWnd uintptr) {
 		if b, ok := arg.Sender.(*TaskItem); ok {
 			// https://github.com/dremin/RetroBar/blob/eb3683d49b8431e2c6e99eb72ea10813eea0d29d/RetroBar/Controls/TaskButton.xaml.cs#L159-L160
 			// BUG: something is still odd but for now this is ok
-			if w32.IsWindowVisible(b.hWnd) {
-				if w32.IsIconic(b.hWnd) {
-					w32.ShowWindow(w32.HWND(b.hWnd), w32.SW_RESTORE)
-					w32.SetForegroundWindow(w32.HWND(w32.GetLastActivePopup(hWnd)))
-				} else {
-					w32.ShowWindow(w32.HWND(b.hWnd), w32.SW_MINIMIZE)
-				}
+			if w32.IsWindowVisible(b.hWnd) && !w32.IsIconic(b.hWnd) {
+				w32.ShowWindow(w32.HWND(b.hWnd), w32.SW_MINIMIZE)
 			} else {
-				w32.ShowWindow(w32.HWND(hWnd), w32.SW_SHOW)
-				w32.SetForegroundWindow(w32.HWND(w32.GetLastActivePopup(hWnd)))
+				ActivateWindow(b.hWnd)
 			}
 		}
 	})
@@ -155,12 +151,12 @@ func (tl *taskList) Contains(hwnd uintptr) int {
 func (tl *taskList) Refresh(parent winc.Controller, refresh bool) {
 	var lastOffset int
 	if tl.centered {
-		lastOffset = (SM_CXSCREEN / 2) - len(tl.PushButtonList)*config.Taskbar.Button.Size.Width/2
+		lastOffset = (SM_CXSCREEN / 2) - len(tl.PushButtonList)*cfg.Taskbar.Button.Size.Width/2
 	}
 
 	var topOffset = 0
-	var TaskbarButtonSizeWidth = config.Taskbar.Button.Size.Width
-	var TaskbarButtonSizeHeight = config.Taskbar.Button.Size.Height
+	var TaskbarButtonSizeWidth = cfg.Taskbar.Button.Size.Width
+	var TaskbarButtonSizeHeight = cfg.Taskbar.Button.Size.Height
 
 	var lastHandle uintptr
 	if len(tl.PushButtonList) != 0 {
@@ -18,6 +18,7 @@ var (
 	actionsByID            = make(map[uint16]*MenuItem)
 	shortcut2Action        = make(map[Shortcut]*MenuItem)
 	menuItems              = make(map[w32.HMENU][]*MenuItem)
+	subMenus               = make(map[w32.HMENU]*MenuItem) // by hSubMenu, for WM_INITMENUPOPUP
 	radioGroups            = make(map[*MenuItem]*RadioGroup)
 	initialised     bool
 )
@@ -49,8 +50,9 @@ type MenuItem struct {
 
 	Command Command
 
-	onClick  EventManager
-	onMClick EventManager
+	onClick     EventManager
+	onMClick    EventManager
+	onInitPopup EventManager
 }
 
 type Command struct {
@@ -218,6 +220,11 @@ func (mi *MenuItem) OnMClick() *EventManager {
 	return &mi.onMClick
 }
 
+// OnInitPopup fires before the submenu opens, the items can still be changed.
+func (mi *MenuItem) OnInitPopup() *EventManager {
+	return &mi.onInitPopup
+}
+
 func (mi *MenuItem) AddSeparator() {
 	addMenuItem(mi.hSubMenu, 0, "-", Shortcut{}, nil, false)
 }
@@ -286,6 +293,9 @@ func addMenuItem(hMenu, hSubMenu w32.HMENU, text string, shortcut Shortcut, imag
 	nextMenuItemID++
 	actionsByID[item.id] = item
 	menuItems[hMenu] = append(menuItems[hMenu], item)
+	if hSubMenu != 0 {
+		subMenus[hSubMenu] = item
+	}
 
 	var mii w32.MENUITEMINFO
 	initMenuItemInfoFromAction(&mii, item)
@@ -297,6 +307,42 @@ func addMenuItem(hMenu, hSubMenu w32.HMENU, text string, shortcut Shortcut, imag
 	return item
 }
 
+// Clear removes all items of a submenu, e.g. to fill it again.
+func (mi *MenuItem) Clear() {
+	items := menuItems[mi.hSubMenu]
+	for i := len(items) - 1; i >= 0; i-- {
+		w32.DeleteMenu(mi.hSubMenu, uint32(i), w32.MF_BYPOSITION)
+		forgetMenuItem(items[i])
+	}
+	delete(menuItems, mi.hSubMenu)
+	// the handles of the destroyed submenus are reused by Windows, an old
+	// entry would get the WM_INITMENUPOPUP of a new menu
+	for hMenu, item := range subMenus {
+		if item.hMenu == mi.hSubMenu && hMenu != mi.hSubMenu {
+			delete(subMenus, hMenu)
+		}
+	}
+}
+
+// forgetMenuItem removes the item and its submenus from the maps, DeleteMenu
+// destroyed the submenus already.
+func forgetMenuItem(item *MenuItem) {
+	delete(actionsByID, item.id)
+	if shortcut2Action[item.shortcut] == item {
+		delete(shortcut2Action, item.shortcut)
+	}
+	delete(radioGroups, item)
+	if item.hSubMenu != 0 {
+		for _, child := range menuItems[item.hSubMenu] {
+			forgetMenuItem(child)
+		}
+		delete(menuItems, item.hSubMenu)
+		if subMenus[item.hSubMenu] == item {
+			delete(subMenus, item.hSubMenu)
+		}
+	}
+}
+
 func indexInObserver(a *MenuItem) int {
 	var idx int
 	for _, mi := range menuItems[a.hMenu] {
@@ -312,6 +358,10 @@ func findMenuItemByID(id int) *MenuItem {
 	return actionsByID[uint16(id)]
 }
 
+func findSubMenu(hMenu w32.HMENU) *MenuItem {
+	return subMenus[hMenu]
+}
+
 func closeAllMenus() {
 	log.Printf("%#v\n", actionsByID)
 	log.Println(len(actionsByID))
@@ -86,6 +86,7 @@ var (
 	procCreateMenu        = moduser32.NewProc("CreateMenu")
 	//procSetMenu                  = moduser32.NewProc("SetMenu")
 	procDestroyMenu        = moduser32.NewProc("DestroyMenu")
+	procDeleteMenu         = moduser32.NewProc("DeleteMenu")
 	procCreatePopupMenu    = moduser32.NewProc("CreatePopupMenu")
 	procCheckMenuRadioItem = moduser32.NewProc("CheckMenuRadioItem")
 	//procDrawMenuBar     = moduser32.NewProc("DrawMenuBar")
@@ -145,7 +146,9 @@ var (
 	procGetWindow                     = moduser32.NewProc("GetWindow")
 	procGetDesktopWindow              = moduser32.NewProc("GetDesktopWindow")
 	procRegisterHotKey                = moduser32.NewProc("RegisterHotKey")
+	procUnregisterHotKey              = moduser32.NewProc("UnregisterHotKey")
 	procSetLayeredWindowAttributes    = moduser32.NewProc("SetLayeredWindowAttributes")
+	procLockWorkStation               = moduser32.NewProc("LockWorkStation")
 
 	procEnumWindows        = moduser32.NewProc("EnumWindows")
 	procGetWindowTextW     = moduser32.NewProc("GetWindowTextW")
@@ -1306,6 +1309,16 @@ func DestroyMenu(hMenu HMENU) bool {
 	return ret != 0
 }
 
+// https://learn.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-deletemenu
+func DeleteMenu(hMenu HMENU, uPosition, uFlags uint32) bool {
+	ret, _, _ := procDeleteMenu.Call(
+		uintptr(hMenu),
+		uintptr(uPosition),
+		uintptr(uFlags))
+
+	return ret != 0
+}
+
 func GetWindowPlacement(hWnd HWND, lpwndpl *WINDOWPLACEMENT) bool {
 	ret, _, _ := syscall.Syscall(getWindowPlacement, 2,
 		uintptr(hWnd),
@@ -1404,6 +1417,23 @@ func RegisterHotKey(hwnd uintptr, id int, fsModifiers, vkey uint32) bool {
 	return ret != 0
 }
 
+// Frees a hot key previously registered by the calling thread.
+// See https://learn.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-unregisterhotkey
+func UnregisterHotKey(hwnd uintptr, id int) bool {
+	ret, _, _ := procUnregisterHotKey.Call(
+		hwnd,
+		uintptr(id),
+	)
+	return ret != 0
+}
+
+// Locks the workstation's display.
+// See https://learn.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-lockworkstation
+func LockWorkStation() bool {
+	ret, _, _ := procLockWorkStation.Call()
+	return ret != 0
+}
+
 // https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-setlayeredwindowattributes
 func SetLayeredWindowAttributes(hwnd uintptr, pcrKey uint32, pbAlpha byte, pdwFlags int32) (err error) {
 	r0, _, err := procSetLayeredWindowAttributes.Call(hwnd,
@@ -151,6 +151,11 @@ func generalWndProc(hwnd w32.HWND, msg uint32, wparam, lparam uintptr) uintptr {
 				}
 			}
 
+		case w32.WM_INITMENUPOPUP:
+			// https://learn.microsoft.com/en-us/windows/win32/menurc/wm-initmenupopup
+			if item := findSubMenu(w32.HMENU(wparam)); item != nil {
+				item.OnInitPopup().Fire(NewEvent(controller, nil))
+			}
 		case w32.WM_MENURBUTTONUP:
 			// log.Println("WM_MENURBUTTONUP", wparam, lparam)
 			// https://devblogs.microsoft.com/oldnewthing/20120104-00/?p=8703
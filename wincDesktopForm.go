@@ -1,3 +1,5 @@
+//go:build windows
+
 package main
 
 import (
@@ -7,6 +9,7 @@ import (
 
 type DesktopForm struct {
 	winc.Form
+	shell *shell
 }
 
 var WM_SHELLHOOK uint32
@@ -55,15 +58,11 @@ func (dlg *DesktopForm) WndProc(msg uint32, wparam, lparam uintptr) uintptr {
 		}
 	case w32.WM_HOTKEY:
 		i := wparam
-
-		switch {
-		case config.Hotkey[i].ShellExecute != "":
-			shellExecute(ResolveVariables(config.Hotkey[i].ShellExecute), config.Hotkey[i].Args, config.Hotkey[i].Hidden)
-		case config.Hotkey[i].CreateProcess != "":
-			createProcess(ResolveVariables(config.Hotkey[i].CreateProcess), config.Hotkey[i].Args, config.Hotkey[i].Hidden)
-		case config.Hotkey[i].OpenProcess != "":
-			openProcess(ResolveVariables(config.Hotkey[i].OpenProcess), config.Hotkey[i].Args, config.Hotkey[i].Hidden)
+		if i >= uintptr(len(cfg.Hotkey)) {
+			break // config was reloaded
 		}
+
+		dlg.shell.Run("", &cfg.Hotkey[i].Action)
 	default:
 		// log.Printf("DesktopForm WndProc (%d, 0x%x)\n", msg, msg)
 	}
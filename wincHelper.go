@@ -1,8 +1,12 @@
+//go:build windows
+
 package main
 
 import (
 	"log"
 
+	"GoShell/config"
+	"github.com/leaanthony/winc"
 	"github.com/leaanthony/winc/w32"
 )
 
@@ -36,3 +40,8 @@ func NewCanvasFromImage(canvashdc uintptr) {
 func SetPos(hwnd uintptr, x, y int) {
 	w32.SetWindowPos(hwnd, w32.HWND_TOP, x, y, 0, 0, w32.SWP_NOSIZE)
 }
+
+// rgb drops the alpha channel, GDI can't draw with it
+func rgb(c config.Color) winc.Color {
+	return winc.RGB(c.R, c.G, c.B)
+}
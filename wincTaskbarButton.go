@@ -1,3 +1,5 @@
+//go:build windows
+
 package main
 
 import (
@@ -22,7 +24,7 @@ func NewTaskItem(parent winc.Controller) *TaskItem {
 
 	pb.SetFont(winc.DefaultFont)
 	pb.SetText("TaskItem")
-	pb.SetSize(config.Taskbar.Button.Size.Width, config.Taskbar.Button.Size.Height)
+	pb.SetSize(cfg.Taskbar.Button.Size.Width, cfg.Taskbar.Button.Size.Height)
 
 	return pb
 }
@@ -1,3 +1,5 @@
+//go:build windows
+
 package main
 
 import (
@@ -77,6 +79,30 @@ func (dlg *TaskbarForm) CheckFullscreen(hWnd uintptr) {
 	}
 }
 
+// SetOpacity makes the whole taskbar translucent, 255 is opaque
+func (dlg *TaskbarForm) SetOpacity(alpha byte) {
+	dlg.mu.Lock()
+	defer dlg.mu.Unlock()
+
+	if alpha == 255 {
+		if dlg.ExStyle&w32.WS_EX_LAYERED != 0 {
+			dlg.ExStyle &^= w32.WS_EX_LAYERED
+			w32.SetWindowLong(dlg.Handle(), w32.GWL_EXSTYLE, dlg.ExStyle)
+		}
+		return
+	}
+
+	if dlg.ExStyle&w32.WS_EX_LAYERED == 0 {
+		dlg.ExStyle |= w32.WS_EX_LAYERED
+		w32.SetWindowLong(dlg.Handle(), w32.GWL_EXSTYLE, dlg.ExStyle)
+	}
+	// https://learn.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-setlayeredwindowattributes
+	const LWA_ALPHA = 0x2
+	if err := w32.SetLayeredWindowAttributes(dlg.Handle(), 0, alpha, LWA_ALPHA); err != nil {
+		log.Println(err)
+	}
+}
+
 func (dlg *TaskbarForm) IsFullscreen(hWnd uintptr) bool {
 	if dlg.IsPrimaryMonitor(hWnd) {
 		// https://learn.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getwindowplacement
@@ -260,16 +286,3 @@ func (tbf *TaskbarForm) GetTaskbarState() w32.APPBARDATA {
 	w32.SHAppBarMessage(w32.ABM_GETSTATE, &abd)
 	return abd
 }
-
-func (tbf *TaskbarForm) ContextMenu() *winc.MenuItem {
-	contextmenu := winc.NewContextMenu()
-
-	hBmp := winc.GetBitmap(ResolveVariables("%SystemRoot%\\system32\\imageres.dll"), -150)
-
-	exitContextMenu := contextmenu.AddItemWithBitmap("Taskmgr", winc.NoShortcut, hBmp)
-	exitContextMenu.OnClick().Bind(func(_ *winc.Event) {
-		shellExecute(ResolveVariables("%SystemRoot%\\system32\\Taskmgr.exe"), []string{}, false)
-	})
-
-	return contextmenu
-}
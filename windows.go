@@ -1,12 +1,26 @@
+//go:build windows
+
 package main
 
 import (
+	"log"
+	"strings"
 	"syscall"
 
 	"github.com/leaanthony/winc"
 	"github.com/leaanthony/winc/w32"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
 )
 
+func UTF16PtrFromString(s string) *uint16 {
+	ret, err := syscall.UTF16PtrFromString(s)
+	if err != nil {
+		log.Println(err)
+	}
+	return ret
+}
+
 func GetProcesses() []uintptr {
 	var tasklist []uintptr
 	cb := syscall.NewCallback(func(h syscall.Handle, p uintptr) uintptr {
@@ -26,6 +40,37 @@ func WindowTitle(hWnd uintptr) string {
 	return ""
 }
 
+// WindowExe returns the path of the program of the window
+func WindowExe(hWnd uintptr) string {
+	var pid uint32
+	if _, err := windows.GetWindowThreadProcessId(windows.HWND(hWnd), &pid); err != nil {
+		return ""
+	}
+	// https://learn.microsoft.com/en-us/windows/win32/api/winbase/nf-winbase-queryfullprocessimagenamew
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(h)
+	b := make([]uint16, windows.MAX_LONG_PATH)
+	size := uint32(len(b))
+	if err := windows.QueryFullProcessImageName(h, 0, &b[0], &size); err != nil {
+		return ""
+	}
+	return syscall.UTF16ToString(b[:size])
+}
+
+// ActivateWindow brings the window to the front like a click on its taskbar button
+func ActivateWindow(hWnd uintptr) {
+	if !w32.IsWindowVisible(hWnd) {
+		w32.ShowWindow(w32.HWND(hWnd), w32.SW_SHOW)
+	}
+	if w32.IsIconic(hWnd) {
+		w32.ShowWindow(w32.HWND(hWnd), w32.SW_RESTORE)
+	}
+	w32.SetForegroundWindow(w32.HWND(w32.GetLastActivePopup(hWnd)))
+}
+
 var (
 	ICON_SMALL  = 0
 	ICON_BIG    = 1
@@ -35,6 +80,18 @@ var (
 	GCL_HICONSM = -34
 )
 
+// ExpandEnvironment replaces the %variables% in s, s stays if it fails
+// https://learn.microsoft.com/en-us/windows/win32/api/processenv/nf-processenv-expandenvironmentstringsw
+func ExpandEnvironment(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	if v, err := registry.ExpandString(s); err == nil {
+		return v
+	}
+	return s
+}
+
 func GetAppIcon(hwnd uintptr) *winc.Icon {
 	// https://stackoverflow.com/a/24052117
 	var iconHandle uintptr